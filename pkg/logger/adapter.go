@@ -0,0 +1,29 @@
+package logger
+
+// PrintfLogger is the logging interface of libraries such as go-zookeeper.
+type PrintfLogger interface {
+	Printf(format string, v ...interface{})
+}
+
+type printfLogger struct {
+	logger Logger
+	level  LogLevel
+}
+
+// NewPrintfLogger adapts l to PrintfLogger, writing every line at level.
+func NewPrintfLogger(l Logger, level LogLevel) PrintfLogger {
+	return &printfLogger{logger: l, level: level}
+}
+
+func (p *printfLogger) Printf(format string, v ...interface{}) {
+	switch p.level {
+	case DebugLevel:
+		p.logger.Debugf(format, v...)
+	case WarnLevel:
+		p.logger.Warnf(format, v...)
+	case ErrorLevel, FatalLevel:
+		p.logger.Errorf(format, v...)
+	default:
+		p.logger.Infof(format, v...)
+	}
+}
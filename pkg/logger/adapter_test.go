@@ -0,0 +1,21 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintfLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(t, "printf", WithOutput(&buf))
+	NewPrintfLogger(l, WarnLevel).Printf("connected to %s", "127.0.0.1:2181")
+	NewPrintfLogger(l, DebugLevel).Printf("hidden")
+
+	out := buf.String()
+	assert.True(t, strings.Contains(out, "level=warning"), out)
+	assert.True(t, strings.Contains(out, "connected to 127.0.0.1:2181"), out)
+	assert.False(t, strings.Contains(out, "hidden"), out)
+}
@@ -0,0 +1,237 @@
+package logger
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy tells an async logger what to do when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop discards the entry and counts it as dropped.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock waits for room in the buffer.
+	OverflowBlock
+)
+
+const defaultAsyncBufferSize = 1024
+
+// AsyncConfig configures WithAsync.
+type AsyncConfig struct {
+	// BufferSize is the number of entries waiting to be written,
+	// defaultAsyncBufferSize when not positive.
+	BufferSize int
+	Overflow   OverflowPolicy
+}
+
+// AsyncStats counts the entries of an async logger.
+type AsyncStats struct {
+	Written uint64
+	Dropped uint64
+}
+
+type asyncEntry struct {
+	w io.Writer
+	b []byte
+	// flushed is closed once every entry queued before it is written
+	flushed chan struct{}
+}
+
+// asyncWriter writes formatted entries from a bounded queue in the
+// background. Writes after Close go straight to their writer.
+type asyncWriter struct {
+	sync.RWMutex
+	queue    chan asyncEntry
+	overflow OverflowPolicy
+	closed   bool
+	done     chan struct{}
+
+	written uint64
+	dropped uint64
+}
+
+func newAsyncWriter(config AsyncConfig) *asyncWriter {
+	size := config.BufferSize
+	if size <= 0 {
+		size = defaultAsyncBufferSize
+	}
+	a := &asyncWriter{
+		queue:    make(chan asyncEntry, size),
+		overflow: config.Overflow,
+		done:     make(chan struct{}),
+	}
+	go a.loop()
+	return a
+}
+
+func (a *asyncWriter) loop() {
+	defer close(a.done)
+	for e := range a.queue {
+		if e.flushed != nil {
+			close(e.flushed)
+			continue
+		}
+		e.w.Write(e.b) //nolint: errcheck
+		atomic.AddUint64(&a.written, 1)
+	}
+}
+
+// write queues a copy of b, logrus reuses its buffers.
+func (a *asyncWriter) write(w io.Writer, b []byte) (int, error) {
+	a.RLock()
+	defer a.RUnlock()
+	if a.closed {
+		return w.Write(b)
+	}
+	e := asyncEntry{w: w, b: append([]byte(nil), b...)}
+	if a.overflow == OverflowBlock {
+		a.queue <- e
+		return len(b), nil
+	}
+	select {
+	case a.queue <- e:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+	return len(b), nil
+}
+
+// Flush waits until every entry queued so far is written.
+func (a *asyncWriter) Flush() {
+	a.RLock()
+	if a.closed {
+		a.RUnlock()
+		return
+	}
+	flushed := make(chan struct{})
+	a.queue <- asyncEntry{flushed: flushed}
+	a.RUnlock()
+	<-flushed
+}
+
+// Close drains the queue and stops the background writer.
+func (a *asyncWriter) Close() {
+	a.Lock()
+	if a.closed {
+		a.Unlock()
+		return
+	}
+	a.closed = true
+	close(a.queue)
+	a.Unlock()
+	<-a.done
+}
+
+func (a *asyncWriter) Stats() AsyncStats {
+	return AsyncStats{
+		Written: atomic.LoadUint64(&a.written),
+		Dropped: atomic.LoadUint64(&a.dropped),
+	}
+}
+
+// asyncOutput is the io.Writer installed as the logrus output.
+type asyncOutput struct {
+	async *asyncWriter
+	w     io.Writer
+}
+
+func (o *asyncOutput) Write(b []byte) (int, error) {
+	return o.async.write(o.w, b)
+}
+
+// EnableAsync moves the writes of the logger and of its sinks to a
+// background goroutine. Fatal drains the queue before exiting.
+func (l *logger) EnableAsync(config AsyncConfig) {
+	if l.async != nil {
+		return
+	}
+	a := newAsyncWriter(config)
+	l.async = a
+	// with sinks the output is discarded, only the sinks use the writer
+	if out := l.logger.Logger.Out; out != ioutil.Discard {
+		l.logger.Logger.SetOutput(&asyncOutput{async: a, w: out})
+	}
+	if h := l.findSinkHook(); h != nil {
+		h.Lock()
+		h.async = a
+		h.Unlock()
+	}
+	l.logger.Logger.ExitFunc = func(code int) {
+		a.Close()
+		os.Exit(code)
+	}
+}
+
+// setOutput replaces the output of the logger, closing the file opened by
+// WithOutputFile.
+func (l *logger) setOutput(w io.Writer) {
+	if l.async != nil && w != ioutil.Discard {
+		w = &asyncOutput{async: l.async, w: w}
+	}
+	l.logger.Logger.SetOutput(w)
+	if l.outputFile != nil {
+		// write what is queued for it before closing it
+		l.Flush()
+		l.outputFile.Close() //nolint: errcheck
+		l.outputFile = nil
+	}
+}
+
+// Flush waits until every entry logged so far is written.
+func (l *logger) Flush() {
+	if l.async != nil {
+		l.async.Flush()
+	}
+}
+
+// Close stops the suppressed entries report, drains and stops the async
+// writer, later entries are written synchronously. It closes the files
+// opened by WithOutputFile and WithSinkFile too: the output goes back to
+// os.Stdout and the sinks of the files are removed.
+func (l *logger) Close() error {
+	l.stopReport()
+	if l.async != nil {
+		l.async.Close()
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	var err error
+	if f := l.outputFile; f != nil {
+		l.outputFile = nil
+		l.logger.Logger.SetOutput(os.Stdout)
+		err = f.Close()
+	}
+	if h := l.findSinkHook(); h != nil {
+		h.Lock()
+		var files []*RotatingFile
+		sinks := h.sinks[:0]
+		for _, s := range h.sinks {
+			if s.file != nil {
+				files = append(files, s.file)
+			} else {
+				sinks = append(sinks, s)
+			}
+		}
+		h.sinks = sinks
+		h.Unlock()
+		for _, f := range files {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
+
+// AsyncStats returns the counters of the async writer, zero when the logger
+// is synchronous.
+func (l *logger) AsyncStats() AsyncStats {
+	if l.async == nil {
+		return AsyncStats{}
+	}
+	return l.async.Stats()
+}
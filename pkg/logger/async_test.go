@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gatedWriter blocks every write until the gate is opened.
+type gatedWriter struct {
+	sync.Mutex
+	gate chan struct{}
+	buf  bytes.Buffer
+}
+
+func (w *gatedWriter) Write(b []byte) (int, error) {
+	<-w.gate
+	w.Lock()
+	defer w.Unlock()
+	return w.buf.Write(b)
+}
+
+func (w *gatedWriter) String() string {
+	w.Lock()
+	defer w.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncDrop(t *testing.T) {
+	w := &gatedWriter{gate: make(chan struct{})}
+	l := newTestLogger(t, "async.drop",
+		WithOutput(w),
+		WithAsync(AsyncConfig{BufferSize: 2, Overflow: OverflowDrop}),
+	)
+	// the first entry is taken by the writer, two are buffered
+	for i := 0; i < 10; i++ {
+		l.Info("entry")
+	}
+	close(w.gate)
+	l.Flush()
+
+	stats := l.AsyncStats()
+	assert.True(t, stats.Dropped >= 7, "dropped %d", stats.Dropped)
+	assert.Equal(t, uint64(10), stats.Written+stats.Dropped)
+	assert.Equal(t, int(stats.Written), strings.Count(w.String(), "entry"))
+
+	assert.Nil(t, l.Close())
+	l.Info("after close")
+	assert.True(t, strings.Contains(w.String(), "after close"))
+}
+
+func TestAsyncBlock(t *testing.T) {
+	var out, errs bytes.Buffer
+	l := newTestLogger(t, "async.block",
+		WithSink(Sink{Writer: &out}),
+		WithSink(Sink{Writer: &errs, Level: ErrorLevel}),
+		WithAsync(AsyncConfig{BufferSize: 1, Overflow: OverflowBlock}),
+	)
+	for i := 0; i < 100; i++ {
+		l.WithField("i", i).Info("entry")
+	}
+	l.Error("failed")
+	Flush()
+
+	// one write per sink entry, none for the discarded output
+	assert.Equal(t, AsyncStats{Written: 102}, l.AsyncStats())
+	assert.Equal(t, 100, strings.Count(out.String(), "entry"))
+	assert.Equal(t, 0, strings.Count(errs.String(), "entry"))
+	assert.True(t, strings.Contains(errs.String(), "failed"))
+	assert.Nil(t, l.Close())
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Field names of request logs.
+const (
+	FieldRequestID  = "request_id"
+	FieldProduct    = "product"
+	FieldClientAddr = "client_addr"
+
+	logFieldDuration = "duration_ms"
+	logFieldOutcome  = "outcome"
+)
+
+// Outcomes of a request log.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx, or the global logger.
+func FromContext(ctx context.Context) Logger {
+	if l, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return l
+	}
+	return Global()
+}
+
+// WithContextFields returns a copy of ctx whose Logger adds fields to every
+// entry.
+func WithContextFields(ctx context.Context, fields Fields) context.Context {
+	return NewContext(ctx, FromContext(ctx).WithFields(fields))
+}
+
+// NewRequestID returns a random 16 hex digits request id.
+func NewRequestID() string {
+	var b [8]byte
+	rand.Read(b[:]) //nolint: errcheck
+	return hex.EncodeToString(b[:])
+}
+
+// Request is one request being served, End writes its request log.
+type Request struct {
+	logger Logger
+	start  time.Time
+}
+
+// StartRequest adds fields to the Logger of ctx, with a new request id
+// unless fields has one, and starts timing the request. The returned
+// context carries the request scoped Logger.
+func StartRequest(ctx context.Context, fields Fields) (context.Context, *Request) {
+	f := make(Fields, len(fields)+1)
+	for k, v := range fields {
+		f[k] = v
+	}
+	if _, ok := f[FieldRequestID]; !ok {
+		f[FieldRequestID] = NewRequestID()
+	}
+	ctx = WithContextFields(ctx, f)
+	return ctx, &Request{logger: FromContext(ctx), start: time.Now()}
+}
+
+// End writes a request type entry with the duration and outcome of the
+// request, at Info on success and at Error with err otherwise.
+func (r *Request) End(msg string, err error) {
+	l := r.logger.WithFields(Fields{
+		logFieldType:     LogTypeRequest,
+		logFieldDuration: float64(time.Since(r.start)) / float64(time.Millisecond),
+	})
+	if err != nil {
+		l.WithField(logFieldOutcome, OutcomeError).WithError(err).Error(msg)
+		return
+	}
+	l.WithField(logFieldOutcome, OutcomeSuccess).Info(msg)
+}
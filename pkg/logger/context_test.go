@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLog(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(t, "request", WithOutput(&buf), WithOutputFormat(true))
+
+	ctx := NewContext(context.Background(), l)
+	ctx, req := StartRequest(ctx, Fields{FieldProduct: "p1", FieldClientAddr: "10.0.0.1:5000"})
+	FromContext(ctx).Info("dispatch")
+	req.End("GET", nil)
+	_, req = StartRequest(ctx, Fields{FieldRequestID: "r2"})
+	req.End("SET", errors.New("slot offline"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	entries := make([]map[string]interface{}, len(lines))
+	for i, line := range lines {
+		assert.Nil(t, json.Unmarshal([]byte(line), &entries[i]))
+	}
+
+	assert.Equal(t, LogTypeLog, entries[0][logFieldType])
+	assert.Equal(t, "p1", entries[0][FieldProduct])
+	id, _ := entries[0][FieldRequestID].(string)
+	assert.Len(t, id, 16)
+
+	assert.Equal(t, LogTypeRequest, entries[1][logFieldType])
+	assert.Equal(t, id, entries[1][FieldRequestID])
+	assert.Equal(t, "10.0.0.1:5000", entries[1][FieldClientAddr])
+	assert.Equal(t, OutcomeSuccess, entries[1][logFieldOutcome])
+	assert.NotNil(t, entries[1][logFieldDuration])
+
+	assert.Equal(t, "r2", entries[2][FieldRequestID])
+	assert.Equal(t, "p1", entries[2][FieldProduct])
+	assert.Equal(t, OutcomeError, entries[2][logFieldOutcome])
+	assert.Equal(t, "slot offline", entries[2][logFieldError])
+	assert.Equal(t, "error", entries[2][logFieldLevel])
+}
+
+func TestFromContextDefault(t *testing.T) {
+	assert.NotNil(t, FromContext(context.Background()))
+	assert.NotPanics(t, func() {
+		ctx := WithContextFields(context.Background(), Fields{FieldProduct: "p1"})
+		FromContext(ctx).Debug("no logger in context")
+	})
+}
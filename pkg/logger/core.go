@@ -13,9 +13,39 @@ type logger struct {
 	name string
 	// loger is the instance of logrus logger
 	logger *logrus.Entry
+	// async is set by EnableAsync and shared with the derived loggers
+	async *asyncWriter
+	// limits samples and rate limits the entries of every derived logger
+	limits *limits
+	// outputFile is the file opened by WithOutputFile, closed once the
+	// output is replaced. Only set on registered loggers, guarded by registry.
+	outputFile *RotatingFile
+}
+
+// NewLogger returns the logger of scope name, creating and registering it on
+// first use. Every call with the same name returns that one logger, so the
+// options of a call change it for all the callers holding it: a level or an
+// output set in one package applies to the others logging in the scope.
+// Applying the same options again changes nothing: a file or a sink already
+// set up is kept, not opened or added twice. An option that fails is logged
+// to the logger and skipped.
+func NewLogger(name string, options ...Option) *logger {
+	registry.Lock()
+	defer registry.Unlock()
+	dl, ok := registry.loggers[name]
+	if !ok {
+		dl = newLogger(name)
+		registry.loggers[name] = dl
+	}
+	for _, o := range options {
+		if err := o(dl); err != nil {
+			dl.WithError(err).Errorf("apply option of logger %s failed", name)
+		}
+	}
+	return dl
 }
 
-func NewLogger(name string, options ...Option) *logger {
+func newLogger(name string) *logger {
 	newLogger := logrus.New()
 	newLogger.SetOutput(os.Stdout)
 
@@ -25,20 +55,26 @@ func NewLogger(name string, options ...Option) *logger {
 			logFieldScope: name,
 			logFieldType:  LogTypeLog,
 		}),
+		limits: newLimits(),
 	}
 
 	dl.EnableJSONOutput(defaultJSONOutput)
-
-	for _, o := range options {
-		o(dl) //nolint: errcheck
-	}
 	return dl
 }
 
 // EnableJSONOutput enables JSON formatted output log.
 func (l *logger) EnableJSONOutput(enabled bool) {
-	var formatter logrus.Formatter
+	hostname, _ := os.Hostname()
+	l.logger.Data = logrus.Fields{
+		logFieldScope:    l.logger.Data[logFieldScope],
+		logFieldType:     LogTypeLog,
+		logFieldInstance: hostname,
+	}
 
+	l.logger.Logger.SetFormatter(newFormatter(enabled))
+}
+
+func newFormatter(json bool) logrus.Formatter {
 	fieldMap := logrus.FieldMap{
 		// If time field name is conflicted, logrus adds "fields." prefix.
 		// So rename to unused field @time to avoid the confliction.
@@ -47,26 +83,16 @@ func (l *logger) EnableJSONOutput(enabled bool) {
 		logrus.FieldKeyMsg:   logFieldMessage,
 	}
 
-	hostname, _ := os.Hostname()
-	l.logger.Data = logrus.Fields{
-		logFieldScope:    l.logger.Data[logFieldScope],
-		logFieldType:     LogTypeLog,
-		logFieldInstance: hostname,
-	}
-
-	if enabled {
-		formatter = &logrus.JSONFormatter{
-			TimestampFormat: time.RFC3339Nano,
-			FieldMap:        fieldMap,
-		}
-	} else {
-		formatter = &logrus.TextFormatter{
+	if json {
+		return &logrus.JSONFormatter{
 			TimestampFormat: time.RFC3339Nano,
 			FieldMap:        fieldMap,
 		}
 	}
-
-	l.logger.Logger.SetFormatter(formatter)
+	return &logrus.TextFormatter{
+		TimestampFormat: time.RFC3339Nano,
+		FieldMap:        fieldMap,
+	}
 }
 
 func toLogrusLevel(lvl LogLevel) logrus.Level {
@@ -80,52 +106,112 @@ func (l *logger) SetOutputLevel(outputLevel LogLevel) {
 	l.logger.Logger.SetLevel(toLogrusLevel(outputLevel))
 }
 
+// OutputLevel returns the log output level.
+func (l *logger) OutputLevel() LogLevel {
+	switch l.logger.Logger.GetLevel() {
+	case logrus.DebugLevel, logrus.TraceLevel:
+		return DebugLevel
+	case logrus.InfoLevel:
+		return InfoLevel
+	case logrus.WarnLevel:
+		return WarnLevel
+	case logrus.ErrorLevel:
+		return ErrorLevel
+	case logrus.FatalLevel, logrus.PanicLevel:
+		return FatalLevel
+	}
+	return UndefinedLevel
+}
+
 // WithLogType specify the log_type field in log. Default value is LogTypeLog.
 func (l *logger) WithLogType(logType string) Logger {
+	return l.derive(l.logger.WithField(logFieldType, logType))
+}
+
+func (l *logger) derive(entry *logrus.Entry) *logger {
 	return &logger{
 		name:   l.name,
-		logger: l.logger.WithField(logFieldType, logType),
+		logger: entry,
+		async:  l.async,
+		limits: l.limits,
+	}
+}
+
+// WithField returns a Logger adding key with value to every entry.
+func (l *logger) WithField(key string, value interface{}) Logger {
+	return l.derive(l.logger.WithField(key, value))
+}
+
+// WithFields returns a Logger adding fields to every entry.
+func (l *logger) WithFields(fields Fields) Logger {
+	return l.derive(l.logger.WithFields(logrus.Fields(fields)))
+}
+
+// WithError returns a Logger adding err as the error field to every entry.
+func (l *logger) WithError(err error) Logger {
+	return l.WithField(logFieldError, err)
+}
+
+// levelEnabler is implemented by the Loggers of this package and the ones
+// they derive, so that NewSlogHandler skips the records they would drop.
+type levelEnabler interface {
+	levelEnabled(level LogLevel) bool
+}
+
+func (l *logger) levelEnabled(level LogLevel) bool {
+	return l.logger.Logger.IsLevelEnabled(toLogrusLevel(level))
+}
+
+func (l *logger) log(level logrus.Level, args ...interface{}) {
+	if l.logger.Logger.IsLevelEnabled(level) && l.limits.allow(level, "", args) {
+		l.logger.Log(level, args...)
+	}
+}
+
+func (l *logger) logf(level logrus.Level, format string, args ...interface{}) {
+	if l.logger.Logger.IsLevelEnabled(level) && l.limits.allow(level, format, args) {
+		l.logger.Logf(level, format, args...)
 	}
 }
 
 // Info logs a message at level Info.
 func (l *logger) Info(args ...interface{}) {
-	l.logger.Log(logrus.InfoLevel, args...)
+	l.log(logrus.InfoLevel, args...)
 }
 
 // Infof logs a message at level Info.
 func (l *logger) Infof(format string, args ...interface{}) {
-	l.logger.Logf(logrus.InfoLevel, format, args...)
+	l.logf(logrus.InfoLevel, format, args...)
 }
 
 // Debug logs a message at level Debug.
 func (l *logger) Debug(args ...interface{}) {
-	l.logger.Log(logrus.DebugLevel, args...)
+	l.log(logrus.DebugLevel, args...)
 }
 
 // Debugf logs a message at level Debug.
 func (l *logger) Debugf(format string, args ...interface{}) {
-	l.logger.Logf(logrus.DebugLevel, format, args...)
+	l.logf(logrus.DebugLevel, format, args...)
 }
 
 // Warn logs a message at level Warn.
 func (l *logger) Warn(args ...interface{}) {
-	l.logger.Log(logrus.WarnLevel, args...)
+	l.log(logrus.WarnLevel, args...)
 }
 
 // Warnf logs a message at level Warn.
 func (l *logger) Warnf(format string, args ...interface{}) {
-	l.logger.Logf(logrus.WarnLevel, format, args...)
+	l.logf(logrus.WarnLevel, format, args...)
 }
 
 // Error logs a message at level Error.
 func (l *logger) Error(args ...interface{}) {
-	l.logger.Log(logrus.ErrorLevel, args...)
+	l.log(logrus.ErrorLevel, args...)
 }
 
 // Errorf logs a message at level Error.
 func (l *logger) Errorf(format string, args ...interface{}) {
-	l.logger.Logf(logrus.ErrorLevel, format, args...)
+	l.logf(logrus.ErrorLevel, format, args...)
 }
 
 // Fatal logs a message at level Fatal then the process will exit with status set to 1.
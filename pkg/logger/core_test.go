@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestLogger is NewLogger dropping the logger from the registry when the
+// test ends, so repeated runs start from a fresh logger.
+func newTestLogger(t *testing.T, name string, options ...Option) *logger {
+	t.Cleanup(func() {
+		registry.Lock()
+		l := registry.loggers[name]
+		delete(registry.loggers, name)
+		registry.Unlock()
+		if l != nil {
+			l.Close()
+		}
+	})
+	return NewLogger(name, options...)
+}
+
+func TestWithFields(t *testing.T) {
+	l := newTestLogger(t, "test", WithOutputFormat(true))
+	var buf bytes.Buffer
+	l.logger.Logger.SetOutput(&buf)
+
+	l.WithField("product", "p1").
+		WithFields(Fields{"slot": 3, "group": 1}).
+		WithError(errors.New("boom")).
+		Info("slot migrated")
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "slot migrated", entry[logFieldMessage])
+	assert.Equal(t, "test", entry[logFieldScope])
+	assert.Equal(t, "p1", entry["product"])
+	assert.Equal(t, float64(3), entry["slot"])
+	assert.Equal(t, float64(1), entry["group"])
+	assert.Equal(t, "boom", entry[logFieldError])
+
+	// the parent logger is left untouched
+	buf.Reset()
+	l.Info("plain")
+	entry = nil
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Nil(t, entry["product"])
+}
+
+func TestNewLoggerShared(t *testing.T) {
+	var buf bytes.Buffer
+	first := newTestLogger(t, "shared", WithOutput(&buf))
+	second := NewLogger("shared", WithOutputLevel(ErrorLevel))
+	assert.True(t, first == second)
+
+	// the level set by the second caller applies to the first one
+	first.Info("dropped")
+	assert.Equal(t, 0, buf.Len())
+	first.Error("kept")
+	assert.True(t, strings.Contains(buf.String(), "kept"))
+}
+
+func TestNewLoggerOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var out, all bytes.Buffer
+	sinkFile := FileConfig{Filename: filepath.Join(dir, "sink.log")}
+	options := []Option{
+		WithSink(Sink{Writer: &all}),
+		WithSinkFile(sinkFile, InfoLevel, false),
+	}
+	l := newTestLogger(t, "options", options...)
+	file := l.sinkFile(sinkFileKey(sinkFile.Filename))
+	assert.NotNil(t, file)
+
+	// the same options again reuse the file and the sinks
+	assert.True(t, l == NewLogger("options", options...))
+	assert.True(t, file == l.sinkFile(sinkFileKey(sinkFile.Filename)))
+	l.Info("once")
+	assert.Equal(t, 1, strings.Count(all.String(), "once"))
+	b, err := ioutil.ReadFile(sinkFile.Filename)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(b), "once"))
+
+	// a replaced file is closed
+	sinkFile.MaxSize = 1 << 20
+	NewLogger("options", WithSinkFile(sinkFile, InfoLevel, false))
+	_, err = file.Write([]byte("closed"))
+	assert.Equal(t, os.ErrClosed, err)
+
+	output := FileConfig{Filename: filepath.Join(dir, "out.log")}
+	l = newTestLogger(t, "options.file", WithOutputFile(output))
+	outFile := l.outputFile
+	NewLogger("options.file", WithOutputFile(output))
+	assert.True(t, outFile == l.outputFile)
+	NewLogger("options.file", WithOutput(&out))
+	_, err = outFile.Write([]byte("closed"))
+	assert.Equal(t, os.ErrClosed, err)
+	assert.Nil(t, l.outputFile)
+
+	// a failing option is logged to the logger
+	NewLogger("options.file", WithOutputFile(FileConfig{}))
+	assert.True(t, strings.Contains(out.String(), "apply option of logger options.file failed"))
+}
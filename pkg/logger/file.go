@@ -0,0 +1,262 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// backupTimeFormat sorts lexically in time order.
+const backupTimeFormat = "20060102T150405.000000000"
+
+// FileConfig describes a log file and when to rotate it.
+type FileConfig struct {
+	// Filename is the file written to, rotated files are kept next to it
+	// as name-<time>.ext.
+	Filename string
+	// MaxSize rotates the file before it grows past this many bytes,
+	// 0 disables size-based rotation.
+	MaxSize int64
+	// MaxAge rotates the file once it is this old, 0 disables age-based
+	// rotation. The age of a file that is not empty when opened counts from
+	// its last modification.
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files kept, 0 keeps them all.
+	MaxBackups int
+	// Compress gzips the rotated files in the background.
+	Compress bool
+	// ReopenOnSIGHUP reopens Filename on SIGHUP, for use with an external
+	// logrotate that moves the file away.
+	ReopenOnSIGHUP bool
+}
+
+// RotatingFile is an io.WriteCloser writing to the file of a FileConfig.
+type RotatingFile struct {
+	sync.Mutex
+	config FileConfig
+
+	file      *os.File
+	size      int64
+	createdAt time.Time
+	closed    bool
+
+	// background compresses the rotated files and removes the old ones
+	background sync.WaitGroup
+	// cleanup serializes the background work
+	cleanup sync.Mutex
+
+	sighup chan os.Signal
+}
+
+// OpenRotatingFile opens config.Filename for appending, creating it and its
+// directory as needed.
+func OpenRotatingFile(config FileConfig) (*RotatingFile, error) {
+	if config.Filename == "" {
+		return nil, errors.New("log file name is empty")
+	}
+	f := &RotatingFile{config: config}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	if config.ReopenOnSIGHUP {
+		f.sighup = make(chan os.Signal, 1)
+		signal.Notify(f.sighup, syscall.SIGHUP)
+		go func(sighup <-chan os.Signal) {
+			for range sighup {
+				f.Reopen() //nolint: errcheck
+			}
+		}(f.sighup)
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.config.Filename), 0755); err != nil {
+		return errors.Wrap(err, "create log directory")
+	}
+	file, err := os.OpenFile(f.config.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "open log file")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrap(err, "stat log file")
+	}
+	f.file, f.size, f.createdAt = file, info.Size(), time.Now()
+	if f.size != 0 {
+		f.createdAt = info.ModTime()
+	}
+	return nil
+}
+
+// Write writes p to the file. When the file could not be opened again by a
+// rotation or Reopen, Write tries to open it first.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.config.MaxSize > 0 && f.size+n > f.config.MaxSize {
+		return true
+	}
+	return f.config.MaxAge > 0 && time.Since(f.createdAt) >= f.config.MaxAge
+}
+
+// Rotate moves the current file aside and starts a new one.
+func (f *RotatingFile) Rotate() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return errors.Wrap(err, "close log file")
+		}
+		f.file = nil
+	}
+
+	ext := filepath.Ext(f.config.Filename)
+	prefix := strings.TrimSuffix(f.config.Filename, ext) + "-"
+	backup := prefix + time.Now().Format(backupTimeFormat) + ext
+	if err := os.Rename(f.config.Filename, backup); err != nil {
+		// keep writing to the current file
+		f.open() //nolint: errcheck
+		return errors.Wrap(err, "rename log file")
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	if !f.config.Compress {
+		return f.removeBackups(prefix, ext)
+	}
+	f.background.Add(1)
+	go func() {
+		defer f.background.Done()
+		f.cleanup.Lock()
+		defer f.cleanup.Unlock()
+		if compressFile(backup) == nil {
+			f.removeBackups(prefix, ext) //nolint: errcheck
+		}
+	}()
+	return nil
+}
+
+// removeBackups keeps the newest MaxBackups rotated files.
+func (f *RotatingFile) removeBackups(prefix, ext string) error {
+	if f.config.MaxBackups <= 0 {
+		return nil
+	}
+	matches, err := filepath.Glob(prefix + "*")
+	if err != nil {
+		return errors.Wrap(err, "list rotated log files")
+	}
+	var backups []string
+	for _, name := range matches {
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], ".gz"), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	sort.Strings(backups)
+	for len(backups) > f.config.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return errors.Wrap(err, "remove rotated log file")
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return errors.Wrap(err, "open rotated log file")
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return errors.Wrap(err, "create compressed log file")
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		return errors.Wrap(err, "compress log file")
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return errors.Wrap(err, "compress log file")
+	}
+	if err := dst.Close(); err != nil {
+		return errors.Wrap(err, "close compressed log file")
+	}
+	return os.Remove(name)
+}
+
+// Reopen closes the file and opens Filename again, so writes go to a new
+// file once logrotate has moved the old one away.
+func (f *RotatingFile) Reopen() error {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	return f.open()
+}
+
+// Close closes the file once the rotated files are compressed.
+func (f *RotatingFile) Close() error {
+	f.Lock()
+	if f.sighup != nil {
+		signal.Stop(f.sighup)
+		close(f.sighup)
+		f.sighup = nil
+	}
+	f.closed = true
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.Unlock()
+	f.background.Wait()
+	return err
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "log", "kit.log")
+	f, err := OpenRotatingFile(FileConfig{
+		Filename:   name,
+		MaxSize:    10,
+		MaxBackups: 2,
+		Compress:   true,
+	})
+	assert.Nil(t, err)
+	defer f.Close()
+
+	for _, line := range []string{"line-1\n", "line-2\n", "line-3\n", "line-4\n"} {
+		_, err := f.Write([]byte(line))
+		assert.Nil(t, err)
+	}
+	f.background.Wait()
+	b, err := ioutil.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Equal(t, "line-4\n", string(b))
+
+	backups, err := filepath.Glob(filepath.Join(dir, "log", "kit-*.log.gz"))
+	assert.Nil(t, err)
+	assert.Len(t, backups, 2)
+	r, err := os.Open(backups[1])
+	assert.Nil(t, err)
+	defer r.Close()
+	zr, err := gzip.NewReader(r)
+	assert.Nil(t, err)
+	b, err = ioutil.ReadAll(zr)
+	assert.Nil(t, err)
+	assert.Equal(t, "line-3\n", string(b))
+
+	// an external logrotate moved the file away
+	assert.Nil(t, os.Rename(name, name+".1"))
+	assert.Nil(t, f.Reopen())
+	_, err = f.Write([]byte("line-5\n"))
+	assert.Nil(t, err)
+	b, err = ioutil.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Equal(t, "line-5\n", string(b))
+}
+
+func TestWithOutputFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "kit.log")
+	l := newTestLogger(t, "file", WithOutputFile(FileConfig{Filename: name}))
+	l.Info("to file")
+	b, err := ioutil.ReadFile(name)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(b), "to file"))
+
+	f := l.outputFile
+	assert.Nil(t, l.Close())
+	assert.Nil(t, l.outputFile)
+	_, err = f.Write([]byte("closed"))
+	assert.Equal(t, os.ErrClosed, err)
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "kit.log")
+	assert.Nil(t, ioutil.WriteFile(name, []byte("old\n"), 0644))
+	old := time.Now().Add(-2 * time.Hour)
+	assert.Nil(t, os.Chtimes(name, old, old))
+
+	f, err := OpenRotatingFile(FileConfig{Filename: name, MaxAge: time.Hour})
+	assert.Nil(t, err)
+	defer f.Close()
+	_, err = f.Write([]byte("new\n"))
+	assert.Nil(t, err)
+	b, err := ioutil.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Equal(t, "new\n", string(b))
+	backups, err := filepath.Glob(filepath.Join(dir, "kit-*.log"))
+	assert.Nil(t, err)
+	assert.Len(t, backups, 1)
+}
+
+func TestRotatingFileReopenFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	logDir := filepath.Join(dir, "log")
+	name := filepath.Join(logDir, "kit.log")
+	f, err := OpenRotatingFile(FileConfig{Filename: name})
+	assert.Nil(t, err)
+	defer f.Close()
+
+	// the directory is replaced by a file, the log file cannot be opened
+	assert.Nil(t, os.Rename(logDir, filepath.Join(dir, "moved")))
+	assert.Nil(t, ioutil.WriteFile(logDir, nil, 0644))
+	assert.NotNil(t, f.Reopen())
+	_, err = f.Write([]byte("lost\n"))
+	assert.NotNil(t, err)
+	assert.NotEqual(t, os.ErrClosed, err)
+
+	// the next write opens it again
+	assert.Nil(t, os.Remove(logDir))
+	_, err = f.Write([]byte("line\n"))
+	assert.Nil(t, err)
+	b, err := ioutil.ReadFile(name)
+	assert.Nil(t, err)
+	assert.Equal(t, "line\n", string(b))
+}
@@ -1,57 +1,100 @@
 package logger
 
-var global Logger
+import "sync/atomic"
 
+// DefaultScope is the scope of the global logger until Init or SetGlobal.
+const DefaultScope = "default"
+
+type holder struct {
+	Logger
+}
+
+var global atomic.Value
+
+func init() {
+	global.Store(holder{NewLogger(DefaultScope)})
+}
+
+// Init makes the logger of scope name the global logger.
 func Init(name string, options ...Option) {
-	global = NewLogger(name, options...)
+	SetGlobal(NewLogger(name, options...))
+}
+
+// SetGlobal makes l the logger of the package functions, nil restores the
+// default one.
+func SetGlobal(l Logger) {
+	if l == nil {
+		l = NewLogger(DefaultScope)
+	}
+	global.Store(holder{l})
+}
+
+// Global returns the logger of the package functions.
+func Global() Logger {
+	return global.Load().(holder).Logger
+}
+
+// WithField returns a Logger adding key with value to every entry.
+func WithField(key string, value interface{}) Logger {
+	return Global().WithField(key, value)
+}
+
+// WithFields returns a Logger adding fields to every entry.
+func WithFields(fields Fields) Logger {
+	return Global().WithFields(fields)
+}
+
+// WithError returns a Logger adding err as the error field to every entry.
+func WithError(err error) Logger {
+	return Global().WithError(err)
 }
 
 // Info logs a message at level Info.
 func Info(args ...interface{}) {
-	global.Info(args...)
+	Global().Info(args...)
 }
 
 // Infof logs a message at level Info.
 func Infof(format string, args ...interface{}) {
-	global.Infof(format, args...)
+	Global().Infof(format, args...)
 }
 
 // Debug logs a message at level Debug.
 func Debug(args ...interface{}) {
-	global.Debug(args...)
+	Global().Debug(args...)
 }
 
 // Debugf logs a message at level Debug.
 func Debugf(format string, args ...interface{}) {
-	global.Debugf(format, args...)
+	Global().Debugf(format, args...)
 }
 
 // Warn logs a message at level Warn.
 func Warn(args ...interface{}) {
-	global.Warn(args...)
+	Global().Warn(args...)
 }
 
 // Warnf logs a message at level Warn.
 func Warnf(format string, args ...interface{}) {
-	global.Warnf(format, args...)
+	Global().Warnf(format, args...)
 }
 
 // Error logs a message at level Error.
 func Error(args ...interface{}) {
-	global.Error(args...)
+	Global().Error(args...)
 }
 
 // Errorf logs a message at level Error.
 func Errorf(format string, args ...interface{}) {
-	global.Errorf(format, args...)
+	Global().Errorf(format, args...)
 }
 
 // Fatal logs a message at level Fatal then the process will exit with status set to 1.
 func Fatal(args ...interface{}) {
-	global.Fatal(args...)
+	Global().Fatal(args...)
 }
 
 // Fatalf logs a message at level Fatal then the process will exit with status set to 1.
 func Fatalf(format string, args ...interface{}) {
-	global.Fatalf(format, args...)
+	Global().Fatalf(format, args...)
 }
@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobalDefault(t *testing.T) {
+	l, ok := Global().(*logger)
+	assert.True(t, ok)
+	assert.Equal(t, DefaultScope, l.name)
+
+	// the package functions work before Init
+	assert.NotPanics(t, func() {
+		Debugf("no init %d", 1)
+		WithField("product", "p1").Debug("no init")
+	})
+}
+
+func TestSetGlobal(t *testing.T) {
+	defer SetGlobal(nil)
+
+	l := newTestLogger(t, "injected", WithOutputFormat(true))
+	var buf bytes.Buffer
+	l.logger.Logger.SetOutput(&buf)
+
+	SetGlobal(l)
+	assert.Equal(t, l, Global())
+	WithField("product", "p1").Info("injected")
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "injected", entry[logFieldScope])
+	assert.Equal(t, "p1", entry["product"])
+
+	SetGlobal(nil)
+	assert.Equal(t, DefaultScope, Global().(*logger).name)
+}
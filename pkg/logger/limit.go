@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultSamplingInterval = time.Second
+
+// SamplingConfig configures WithSampling. Within every Interval the first
+// First entries with the same level and message template are logged, then
+// every Thereafter-th, or none when Thereafter is 0.
+type SamplingConfig struct {
+	Interval   time.Duration
+	First      int
+	Thereafter int
+}
+
+// SuppressedStats counts the entries a logger didn't write.
+type SuppressedStats struct {
+	Sampled     uint64
+	RateLimited uint64
+}
+
+type sampleKey struct {
+	level    logrus.Level
+	template string
+}
+
+// limits holds the sampler and rate limiter of a logger, it is shared with
+// the loggers derived from it.
+type limits struct {
+	sync.Mutex
+
+	sampling    *SamplingConfig
+	windowStart time.Time
+	counts      map[sampleKey]int
+
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	total    SuppressedStats
+	reported SuppressedStats
+	stop     chan struct{}
+}
+
+func newLimits() *limits {
+	return &limits{}
+}
+
+// allow reports whether an entry is written. The template is format, or
+// the message of the args without one. Fatal entries are always written.
+func (m *limits) allow(level logrus.Level, format string, args []interface{}) bool {
+	if level <= logrus.FatalLevel {
+		return true
+	}
+	m.Lock()
+	defer m.Unlock()
+	if m.sampling == nil && m.rate <= 0 {
+		return true
+	}
+	now := time.Now()
+	if m.sampling != nil {
+		template := format
+		if template == "" {
+			template = fmt.Sprint(args...)
+		}
+		if !m.sample(now, sampleKey{level, template}) {
+			m.total.Sampled++
+			return false
+		}
+	}
+	if m.rate > 0 && !m.take(now) {
+		m.total.RateLimited++
+		return false
+	}
+	return true
+}
+
+func (m *limits) sample(now time.Time, key sampleKey) bool {
+	// starting a new window forgets every template, which also bounds the
+	// map by the templates seen in one interval
+	if now.Sub(m.windowStart) >= m.sampling.Interval {
+		m.windowStart = now
+		m.counts = make(map[sampleKey]int)
+	}
+	m.counts[key]++
+	n := m.counts[key]
+	if n <= m.sampling.First {
+		return true
+	}
+	return m.sampling.Thereafter > 0 && (n-m.sampling.First)%m.sampling.Thereafter == 0
+}
+
+// take refills the token bucket and spends one token.
+func (m *limits) take(now time.Time) bool {
+	if m.last.IsZero() {
+		m.tokens = m.burst
+	} else if m.tokens += now.Sub(m.last).Seconds() * m.rate; m.tokens > m.burst {
+		m.tokens = m.burst
+	}
+	m.last = now
+	if m.tokens < 1 {
+		return false
+	}
+	m.tokens--
+	return true
+}
+
+func (m *limits) setSampling(config SamplingConfig) {
+	if config.Interval <= 0 {
+		config.Interval = defaultSamplingInterval
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.sampling = &config
+	m.counts = make(map[sampleKey]int)
+	m.windowStart = time.Time{}
+}
+
+func (m *limits) setRateLimit(rate float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.rate, m.burst = rate, float64(burst)
+	m.last = time.Time{}
+}
+
+func (m *limits) stats() SuppressedStats {
+	m.Lock()
+	defer m.Unlock()
+	return m.total
+}
+
+// unreported returns the entries suppressed since the last call.
+func (m *limits) unreported() SuppressedStats {
+	m.Lock()
+	defer m.Unlock()
+	s := SuppressedStats{
+		Sampled:     m.total.Sampled - m.reported.Sampled,
+		RateLimited: m.total.RateLimited - m.reported.RateLimited,
+	}
+	m.reported = m.total
+	return s
+}
+
+// startReport logs the suppressed counts at Warn every interval in which
+// anything was suppressed, until stopReport.
+func (l *logger) startReport(interval time.Duration) {
+	m := l.limits
+	m.Lock()
+	if m.stop != nil {
+		close(m.stop)
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+	m.Unlock()
+
+	entry := l.logger
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			s := m.unreported()
+			if s.Sampled == 0 && s.RateLimited == 0 {
+				continue
+			}
+			entry.WithFields(logrus.Fields{
+				"sampled":      s.Sampled,
+				"rate_limited": s.RateLimited,
+			}).Log(logrus.WarnLevel, fmt.Sprintf("suppressed %d log entries", s.Sampled+s.RateLimited))
+		}
+	}()
+}
+
+func (l *logger) stopReport() {
+	m := l.limits
+	m.Lock()
+	defer m.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// SuppressedStats returns the entries dropped by sampling and rate limiting.
+func (l *logger) SuppressedStats() SuppressedStats {
+	return l.limits.stats()
+}
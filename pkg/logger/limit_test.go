@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type syncBuffer struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.Lock()
+	defer b.Unlock()
+	return b.buf.String()
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLogger(t, "sampling",
+		WithOutput(&buf),
+		WithSampling(SamplingConfig{Interval: time.Hour, First: 3, Thereafter: 5}),
+	)
+	for i := 0; i < 20; i++ {
+		l.Infof("rewatch node %d", i)
+		l.Warn("session expired")
+	}
+	// entries 1, 2, 3, 8, 13 and 18 of each template
+	assert.Equal(t, 6, strings.Count(buf.String(), "rewatch node"))
+	assert.Equal(t, 6, strings.Count(buf.String(), "session expired"))
+	assert.Equal(t, SuppressedStats{Sampled: 28}, l.SuppressedStats())
+
+	// entries below the output level are not counted
+	l.Debugf("rewatch node %d", 0)
+	assert.Equal(t, uint64(28), l.SuppressedStats().Sampled)
+}
+
+func TestRateLimit(t *testing.T) {
+	buf := &syncBuffer{}
+	l := newTestLogger(t, "ratelimit",
+		WithOutput(buf),
+		WithRateLimit(0.001, 2),
+		WithSuppressedReport(time.Millisecond*10),
+	)
+	for i := 0; i < 5; i++ {
+		l.WithField("i", i).Info("entry")
+	}
+	assert.Equal(t, 2, strings.Count(buf.String(), "entry"))
+	assert.Equal(t, SuppressedStats{RateLimited: 3}, l.SuppressedStats())
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(buf.String(), "suppressed 3 log entries") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 5)
+	}
+	assert.True(t, strings.Contains(buf.String(), "rate_limited=3"), buf.String())
+}
@@ -24,6 +24,7 @@ const (
 	logFieldInstance  = "instance"
 	logFieldDaprVer   = "ver"
 	logFieldAppID     = "app_id"
+	logFieldError     = "error"
 )
 
 // LogLevel is Logger Level type.
@@ -45,8 +46,19 @@ const (
 	UndefinedLevel LogLevel = "undefined"
 )
 
+// Fields are structured key/value pairs written as separate fields of the
+// log entry, e.g. in the JSON output.
+type Fields map[string]interface{}
+
 // Logger includes the logging api sets.
 type Logger interface {
+	// WithField returns a Logger adding key with value to every entry.
+	WithField(key string, value interface{}) Logger
+	// WithFields returns a Logger adding fields to every entry.
+	WithFields(fields Fields) Logger
+	// WithError returns a Logger adding err as the error field to every entry.
+	WithError(err error) Logger
+
 	// Info logs a message at level Info.
 	Info(args ...interface{})
 	// Infof logs a message at level Info.
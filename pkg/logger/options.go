@@ -1,6 +1,9 @@
 package logger
 
 import (
+	"io"
+	"time"
+
 	"github.com/pkg/errors"
 )
 
@@ -41,3 +44,113 @@ func WithOutputFormat(enableJson bool) Option {
 		return nil
 	}
 }
+
+// WithOutput sets the writer the log is written to, os.Stdout by default.
+func WithOutput(w io.Writer) Option {
+	return func(l *logger) error {
+		l.setOutput(w)
+		return nil
+	}
+}
+
+// WithOutputFile writes the log to a RotatingFile opened from config. The
+// file stays open while config is unchanged, and is closed once the output
+// is replaced.
+func WithOutputFile(config FileConfig) Option {
+	return func(l *logger) error {
+		if l.outputFile != nil && l.outputFile.config == config {
+			return nil
+		}
+		f, err := OpenRotatingFile(config)
+		if err != nil {
+			return err
+		}
+		l.setOutput(f)
+		l.outputFile = f
+		return nil
+	}
+}
+
+func checkSinkLevel(level LogLevel) error {
+	if level != "" && toLogLevel(string(level)) == UndefinedLevel {
+		return errors.Errorf("undefined Log Output Level: %s", level)
+	}
+	return nil
+}
+
+// WithSink adds a sink to the logger, see Sink. Adding a sink of the same
+// Writer again replaces it.
+func WithSink(s Sink) Option {
+	return func(l *logger) error {
+		if err := checkSinkLevel(s.Level); err != nil {
+			return err
+		}
+		l.AddSink(s)
+		return nil
+	}
+}
+
+// WithSinkFile adds a sink writing to a RotatingFile opened from config.
+// Adding a sink of the same Filename again replaces it, the file stays open
+// while config is unchanged.
+func WithSinkFile(config FileConfig, level LogLevel, json bool) Option {
+	return func(l *logger) error {
+		if err := checkSinkLevel(level); err != nil {
+			return err
+		}
+		key := sinkFileKey(config.Filename)
+		f := l.sinkFile(key)
+		if f == nil || f.config != config {
+			var err error
+			if f, err = OpenRotatingFile(config); err != nil {
+				return err
+			}
+		}
+		l.addSink(key, Sink{Writer: f, Level: level, JSON: json}, f)
+		return nil
+	}
+}
+
+// WithAsync writes the log from a background goroutine, see AsyncConfig.
+// Call Flush before the process exits to not lose the buffered entries.
+func WithAsync(config AsyncConfig) Option {
+	return func(l *logger) error {
+		l.EnableAsync(config)
+		return nil
+	}
+}
+
+// WithSampling samples the entries of the logger, see SamplingConfig.
+func WithSampling(config SamplingConfig) Option {
+	return func(l *logger) error {
+		if config.First < 0 || config.Thereafter < 0 {
+			return errors.Errorf("invalid sampling first = %d, thereafter = %d", config.First, config.Thereafter)
+		}
+		l.limits.setSampling(config)
+		return nil
+	}
+}
+
+// WithRateLimit limits the logger to perSecond entries per second on
+// average with bursts of up to burst entries.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(l *logger) error {
+		if perSecond <= 0 {
+			return errors.Errorf("invalid rate limit = %v", perSecond)
+		}
+		l.limits.setRateLimit(perSecond, burst)
+		return nil
+	}
+}
+
+// WithSuppressedReport logs the number of entries dropped by sampling and
+// rate limiting at Warn every interval in which there were any.
+func WithSuppressedReport(interval time.Duration) Option {
+	return func(l *logger) error {
+		if interval <= 0 {
+			return errors.Errorf("invalid report interval = %s", interval)
+		}
+		l.startReport(interval)
+		return nil
+	}
+}
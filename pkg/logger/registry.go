@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var ErrUnknownScope = errors.New("unknown logger scope")
+
+var registry = struct {
+	sync.Mutex
+	loggers map[string]*logger
+}{loggers: make(map[string]*logger)}
+
+// Scopes returns the names of the registered loggers.
+func Scopes() []string {
+	registry.Lock()
+	defer registry.Unlock()
+	scopes := make([]string, 0, len(registry.loggers))
+	for name := range registry.loggers {
+		scopes = append(scopes, name)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// ScopeLevels returns the output level of every registered logger.
+func ScopeLevels() map[string]LogLevel {
+	registry.Lock()
+	defer registry.Unlock()
+	levels := make(map[string]LogLevel, len(registry.loggers))
+	for name, l := range registry.loggers {
+		levels[name] = l.OutputLevel()
+	}
+	return levels
+}
+
+// Flush waits until the entries of every registered async logger are written.
+func Flush() {
+	registry.Lock()
+	loggers := make([]*logger, 0, len(registry.loggers))
+	for _, l := range registry.loggers {
+		loggers = append(loggers, l)
+	}
+	registry.Unlock()
+	for _, l := range loggers {
+		l.Flush()
+	}
+}
+
+// SetScopeLevel sets the output level of the logger of scope, or of every
+// registered logger when scope is empty.
+func SetScopeLevel(scope string, level LogLevel) error {
+	if level == UndefinedLevel {
+		return errors.Errorf("undefined Log Output Level: %s", level)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if scope == "" {
+		for _, l := range registry.loggers {
+			l.SetOutputLevel(level)
+		}
+		return nil
+	}
+	l, ok := registry.loggers[scope]
+	if !ok {
+		return errors.Wrapf(ErrUnknownScope, "scope %s", scope)
+	}
+	l.SetOutputLevel(level)
+	return nil
+}
+
+type levelRequest struct {
+	Level string `json:"level"`
+}
+
+// LevelHandler serves the output levels of the registered loggers as a JSON
+// object of scope to level. The scope query parameter restricts a request
+// to one logger. PUT takes {"level": "debug"} and sets the level of the
+// scope, or of every logger without it.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope := r.URL.Query().Get("scope")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req levelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := SetScopeLevel(scope, toLogLevel(req.Level)); err != nil {
+				if errors.Cause(err) == ErrUnknownScope {
+					http.Error(w, err.Error(), http.StatusNotFound)
+				} else {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				}
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		levels := ScopeLevels()
+		if scope != "" {
+			level, ok := levels[scope]
+			if !ok {
+				http.Error(w, ErrUnknownScope.Error(), http.StatusNotFound)
+				return
+			}
+			levels = map[string]LogLevel{scope: level}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(levels) //nolint: errcheck
+	})
+}
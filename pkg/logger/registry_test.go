@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelHandler(t *testing.T) {
+	zk := newTestLogger(t, "registry.zk")
+	etcd := newTestLogger(t, "registry.etcd", WithOutputLevel(WarnLevel))
+	assert.True(t, zk == NewLogger("registry.zk"))
+	assert.Equal(t, InfoLevel, zk.OutputLevel())
+
+	h := LevelHandler()
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+
+	w := do(http.MethodGet, "/?scope=registry.etcd", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"registry.etcd":"warn"}`, strings.TrimSpace(w.Body.String()))
+
+	w = do(http.MethodPut, "/?scope=registry.zk", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, DebugLevel, zk.OutputLevel())
+	assert.Equal(t, WarnLevel, etcd.OutputLevel())
+
+	w = do(http.MethodPut, "/", `{"level":"error"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ErrorLevel, zk.OutputLevel())
+	assert.Equal(t, ErrorLevel, etcd.OutputLevel())
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/?scope=missing", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/?scope=missing", `{"level":"info"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/?scope=registry.zk", `{"level":"loud"}`).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodPost, "/", "").Code)
+}
@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sink is one destination of a logger. Entries below the output level of
+// the logger never reach any sink, Level only filters further.
+type Sink struct {
+	Writer io.Writer
+	// Level is the minimum level written, empty writes every entry.
+	Level LogLevel
+	// JSON selects the JSON formatter instead of the text one.
+	JSON bool
+}
+
+type sink struct {
+	// key identifies the sink, adding one of the same key replaces it
+	key    interface{}
+	writer io.Writer
+	// file is the RotatingFile opened for the sink by WithSinkFile
+	file      *RotatingFile
+	level     logrus.Level
+	formatter logrus.Formatter
+}
+
+// sinkHook fans every entry out to the sinks, the logrus output itself is
+// discarded once a sink is added.
+type sinkHook struct {
+	sync.Mutex
+	sinks []sink
+	async *asyncWriter
+}
+
+func (h *sinkHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *sinkHook) Fire(entry *logrus.Entry) error {
+	h.Lock()
+	defer h.Unlock()
+	var lastErr error
+	for _, s := range h.sinks {
+		if entry.Level > s.level {
+			continue
+		}
+		b, err := s.formatter.Format(entry)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if h.async != nil {
+			_, err = h.async.write(s.writer, b)
+		} else {
+			_, err = s.writer.Write(b)
+		}
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (l *logger) findSinkHook() *sinkHook {
+	for _, hook := range l.logger.Logger.Hooks[logrus.InfoLevel] {
+		if h, ok := hook.(*sinkHook); ok {
+			return h
+		}
+	}
+	return nil
+}
+
+func (l *logger) sinkHook() *sinkHook {
+	if h := l.findSinkHook(); h != nil {
+		return h
+	}
+	h := &sinkHook{async: l.async}
+	l.logger.Logger.AddHook(h)
+	l.setOutput(ioutil.Discard)
+	return h
+}
+
+// sinkFileKey is the key of the sinks added by WithSinkFile.
+type sinkFileKey string
+
+// sinkFile returns the file of the sink of key, nil when there is none.
+func (l *logger) sinkFile(key sinkFileKey) *RotatingFile {
+	h := l.findSinkHook()
+	if h == nil {
+		return nil
+	}
+	h.Lock()
+	defer h.Unlock()
+	for _, s := range h.sinks {
+		if s.key == key {
+			return s.file
+		}
+	}
+	return nil
+}
+
+// AddSink makes the logger write to s as well, the first sink replaces the
+// output set by WithOutput or WithOutputFile. A sink of the same Writer is
+// replaced.
+func (l *logger) AddSink(s Sink) {
+	var key interface{}
+	if s.Writer != nil && reflect.TypeOf(s.Writer).Comparable() {
+		key = s.Writer
+	}
+	l.addSink(key, s, nil)
+}
+
+// addSink adds s, or replaces the sink of key unless key is nil. The file
+// of the replaced sink is closed unless it is file.
+func (l *logger) addSink(key interface{}, s Sink, file *RotatingFile) {
+	level := logrus.TraceLevel
+	if s.Level != "" {
+		level = toLogrusLevel(s.Level)
+	}
+	added := sink{
+		key:       key,
+		writer:    s.Writer,
+		file:      file,
+		level:     level,
+		formatter: newFormatter(s.JSON),
+	}
+	h := l.sinkHook()
+	h.Lock()
+	var replaced *RotatingFile
+	i := 0
+	for ; i < len(h.sinks); i++ {
+		if key != nil && h.sinks[i].key == key {
+			replaced = h.sinks[i].file
+			break
+		}
+	}
+	if i < len(h.sinks) {
+		h.sinks[i] = added
+	} else {
+		h.sinks = append(h.sinks, added)
+	}
+	h.Unlock()
+	if replaced != nil && replaced != file {
+		// write what is queued for it before closing it
+		l.Flush()
+		replaced.Close() //nolint: errcheck
+	}
+}
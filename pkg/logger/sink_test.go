@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSinks(t *testing.T) {
+	var all, errs bytes.Buffer
+	l := newTestLogger(t, "sinks",
+		WithOutputLevel(DebugLevel),
+		WithSink(Sink{Writer: &all}),
+		WithSink(Sink{Writer: &errs, Level: ErrorLevel, JSON: true}),
+	)
+
+	l.Debug("debug line")
+	l.WithField("slot", 1).Error("error line")
+
+	lines := strings.Split(strings.TrimSpace(all.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.Contains(lines[0], "debug line"))
+	assert.True(t, strings.Contains(lines[1], "error line"))
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(errs.Bytes(), &entry))
+	assert.Equal(t, "error line", entry[logFieldMessage])
+	assert.Equal(t, "error", entry[logFieldLevel])
+	assert.Equal(t, float64(1), entry["slot"])
+	assert.Equal(t, "sinks", entry[logFieldScope])
+
+	assert.Nil(t, WithSink(Sink{Writer: &all, Level: ErrorLevel})(l))
+	assert.NotNil(t, WithSink(Sink{Writer: &all, Level: "loud"})(l))
+}
@@ -0,0 +1,252 @@
+//go:build go1.21
+// +build go1.21
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+)
+
+// SlogLevelFatal is the slog level of Fatal entries.
+const SlogLevelFatal = slog.LevelError + 4
+
+type slogLogger struct {
+	handler slog.Handler
+}
+
+// NewSlogLogger returns a Logger of scope name writing to h, with the scope,
+// instance and type fields of the logrus output. The level is the one of the
+// slog record, which the slog handlers write under slog.LevelKey, "level" as
+// in the logrus output. Fatal entries are written at SlogLevelFatal, then the
+// process exits.
+func NewSlogLogger(name string, h slog.Handler) Logger {
+	hostname, _ := os.Hostname()
+	return &slogLogger{h.WithAttrs([]slog.Attr{
+		slog.String(logFieldScope, name),
+		slog.String(logFieldInstance, hostname),
+		slog.String(logFieldType, LogTypeLog),
+	})}
+}
+
+// WithField returns a Logger adding key with value to every entry.
+func (s *slogLogger) WithField(key string, value interface{}) Logger {
+	return &slogLogger{s.handler.WithAttrs([]slog.Attr{slog.Any(key, value)})}
+}
+
+// WithFields returns a Logger adding fields to every entry.
+func (s *slogLogger) WithFields(fields Fields) Logger {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	return &slogLogger{s.handler.WithAttrs(attrs)}
+}
+
+// WithError returns a Logger adding err as the error field to every entry.
+func (s *slogLogger) WithError(err error) Logger {
+	return s.WithField(logFieldError, err)
+}
+
+func (s *slogLogger) levelEnabled(level LogLevel) bool {
+	return s.handler.Enabled(context.Background(), toSlogLevel(level))
+}
+
+func toSlogLevel(level LogLevel) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case WarnLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	case FatalLevel:
+		return SlogLevelFatal
+	}
+	return slog.LevelInfo
+}
+
+func (s *slogLogger) log(level slog.Level, args ...interface{}) {
+	if s.handler.Enabled(context.Background(), level) {
+		s.write(level, fmt.Sprint(args...))
+	}
+}
+
+func (s *slogLogger) logf(level slog.Level, format string, args ...interface{}) {
+	if s.handler.Enabled(context.Background(), level) {
+		s.write(level, fmt.Sprintf(format, args...))
+	}
+}
+
+func (s *slogLogger) write(level slog.Level, msg string) {
+	s.handler.Handle(context.Background(), slog.NewRecord(time.Now(), level, msg, 0)) //nolint: errcheck
+}
+
+// Info logs a message at level Info.
+func (s *slogLogger) Info(args ...interface{}) {
+	s.log(slog.LevelInfo, args...)
+}
+
+// Infof logs a message at level Info.
+func (s *slogLogger) Infof(format string, args ...interface{}) {
+	s.logf(slog.LevelInfo, format, args...)
+}
+
+// Debug logs a message at level Debug.
+func (s *slogLogger) Debug(args ...interface{}) {
+	s.log(slog.LevelDebug, args...)
+}
+
+// Debugf logs a message at level Debug.
+func (s *slogLogger) Debugf(format string, args ...interface{}) {
+	s.logf(slog.LevelDebug, format, args...)
+}
+
+// Warn logs a message at level Warn.
+func (s *slogLogger) Warn(args ...interface{}) {
+	s.log(slog.LevelWarn, args...)
+}
+
+// Warnf logs a message at level Warn.
+func (s *slogLogger) Warnf(format string, args ...interface{}) {
+	s.logf(slog.LevelWarn, format, args...)
+}
+
+// Error logs a message at level Error.
+func (s *slogLogger) Error(args ...interface{}) {
+	s.log(slog.LevelError, args...)
+}
+
+// Errorf logs a message at level Error.
+func (s *slogLogger) Errorf(format string, args ...interface{}) {
+	s.logf(slog.LevelError, format, args...)
+}
+
+// Fatal logs a message at level Fatal then the process will exit with status set to 1.
+func (s *slogLogger) Fatal(args ...interface{}) {
+	s.log(SlogLevelFatal, args...)
+	os.Exit(1)
+}
+
+// Fatalf logs a message at level Fatal then the process will exit with status set to 1.
+func (s *slogLogger) Fatalf(format string, args ...interface{}) {
+	s.logf(SlogLevelFatal, format, args...)
+	os.Exit(1)
+}
+
+// slogHandler is an slog.Handler writing through a Logger.
+type slogHandler struct {
+	logger Logger
+	group  string
+}
+
+// NewSlogHandler returns an slog.Handler writing records through l, so they
+// get the scope, instance and level fields, the output and the limits of l.
+// Records below Info are written at Debug, from Error on at Error. Groups
+// prefix the keys of their attributes with "<group>.", and attributes named
+// like the scope, instance or type fields get a "fields." prefix, as logrus
+// does for level, msg and time.
+func NewSlogHandler(l Logger) slog.Handler {
+	return &slogHandler{logger: l}
+}
+
+// Enabled reports whether the Logger writes records of level, always true
+// for Loggers from outside this package.
+func (h *slogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if l, ok := h.logger.(levelEnabler); ok {
+		return l.levelEnabled(slogToLogLevel(level))
+	}
+	return true
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	l := h.logger
+	if r.NumAttrs() != 0 {
+		fields := make(Fields, r.NumAttrs())
+		r.Attrs(func(a slog.Attr) bool {
+			addSlogAttr(fields, h.group, a)
+			return true
+		})
+		l = l.WithFields(fields)
+	}
+	switch slogToLogLevel(r.Level) {
+	case DebugLevel:
+		l.Debug(r.Message)
+	case InfoLevel:
+		l.Info(r.Message)
+	case WarnLevel:
+		l.Warn(r.Message)
+	default:
+		l.Error(r.Message)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make(Fields, len(attrs))
+	for _, a := range attrs {
+		addSlogAttr(fields, h.group, a)
+	}
+	return &slogHandler{logger: h.logger.WithFields(fields), group: h.group}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, group: slogKey(h.group, name)}
+}
+
+func slogKey(group, key string) string {
+	if group == "" {
+		return key
+	}
+	return group + "." + key
+}
+
+func addSlogAttr(fields Fields, group string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		// an inline group has no key
+		if a.Key != "" {
+			group = slogKey(group, a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			addSlogAttr(fields, group, ga)
+		}
+		return
+	}
+	key := slogKey(group, a.Key)
+	switch key {
+	case logFieldScope, logFieldInstance, logFieldType:
+		key = "fields." + key
+	}
+	fields[key] = a.Value.Any()
+}
+
+func slogToLogLevel(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLevel
+	case level < slog.LevelWarn:
+		return InfoLevel
+	case level < slog.LevelError:
+		return WarnLevel
+	}
+	return ErrorLevel
+}
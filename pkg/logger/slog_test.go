@@ -0,0 +1,82 @@
+//go:build go1.21
+// +build go1.21
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger("slog", slog.NewJSONHandler(&buf, nil))
+
+	l.Debugf("dropped %d", 1)
+	l.WithField("product", "p1").WithError(errors.New("boom")).Warnf("slot %d offline", 3)
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	hostname, _ := os.Hostname()
+	assert.Equal(t, "WARN", entry[logFieldLevel])
+	assert.Equal(t, "slot 3 offline", entry[slog.MessageKey])
+	assert.Equal(t, "slog", entry[logFieldScope])
+	assert.Equal(t, hostname, entry[logFieldInstance])
+	assert.Equal(t, "p1", entry["product"])
+	assert.Equal(t, "boom", entry[logFieldError])
+}
+
+func TestSlogHandler(t *testing.T) {
+	l := newTestLogger(t, "slog-handler", WithOutputFormat(true))
+	var buf bytes.Buffer
+	l.logger.Logger.SetOutput(&buf)
+
+	s := slog.New(NewSlogHandler(l))
+	s.Debug("dropped")
+	assert.Zero(t, buf.Len())
+
+	s.With("product", "p1").WithGroup("slot").
+		Warn("slot offline", "id", 3, slog.Group("target", "group", 2), logFieldScope, "other")
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "warning", entry[logFieldLevel])
+	assert.Equal(t, "slot offline", entry[logFieldMessage])
+	assert.Equal(t, "slog-handler", entry[logFieldScope])
+	assert.NotEmpty(t, entry[logFieldInstance])
+	assert.Equal(t, "p1", entry["product"])
+	assert.EqualValues(t, 3, entry["slot.id"])
+	assert.EqualValues(t, 2, entry["slot.target.group"])
+	assert.Equal(t, "other", entry["slot.scope"])
+
+	buf.Reset()
+	s.Error("failed", logFieldScope, "other")
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "error", entry[logFieldLevel])
+	assert.Equal(t, "slog-handler", entry[logFieldScope])
+	assert.Equal(t, "other", entry["fields."+logFieldScope])
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	core, _ := observer.New(zapcore.InfoLevel)
+	for _, l := range []Logger{
+		newTestLogger(t, "slog-enabled").WithField("product", "p1"),
+		NewZapLogger("zap", core).WithField("product", "p1"),
+		NewSlogLogger("slog", slog.NewJSONHandler(ioutil.Discard, nil)).WithField("product", "p1"),
+	} {
+		h := NewSlogHandler(l).WithAttrs([]slog.Attr{slog.Int("slot", 1)})
+		assert.False(t, h.Enabled(context.Background(), slog.LevelDebug))
+		assert.True(t, h.Enabled(context.Background(), slog.LevelInfo))
+		assert.True(t, h.Enabled(context.Background(), slog.LevelError))
+	}
+}
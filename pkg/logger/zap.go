@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// NewZapLogger returns a Logger of scope name writing to core, with the
+// scope, instance and type fields of the logrus output. The level is the
+// one of the zap entry, the encoder of core writes it under its LevelKey,
+// "level" in the zap presets as in the logrus output. Fatal exits the
+// process once core has written the entry.
+func NewZapLogger(name string, core zapcore.Core) Logger {
+	hostname, _ := os.Hostname()
+	return &zapLogger{zap.New(core).With(
+		zap.String(logFieldScope, name),
+		zap.String(logFieldInstance, hostname),
+		zap.String(logFieldType, LogTypeLog),
+	)}
+}
+
+// WithField returns a Logger adding key with value to every entry.
+func (z *zapLogger) WithField(key string, value interface{}) Logger {
+	return &zapLogger{z.logger.With(zap.Any(key, value))}
+}
+
+// WithFields returns a Logger adding fields to every entry.
+func (z *zapLogger) WithFields(fields Fields) Logger {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	zf := make([]zap.Field, 0, len(fields))
+	for _, k := range keys {
+		zf = append(zf, zap.Any(k, fields[k]))
+	}
+	return &zapLogger{z.logger.With(zf...)}
+}
+
+// WithError returns a Logger adding err as the error field to every entry.
+func (z *zapLogger) WithError(err error) Logger {
+	return &zapLogger{z.logger.With(zap.NamedError(logFieldError, err))}
+}
+
+// enabled skips formatting messages the core drops, Fatal always goes
+// through Check so that it exits.
+func (z *zapLogger) enabled(level zapcore.Level) bool {
+	return level >= zapcore.FatalLevel || z.logger.Core().Enabled(level)
+}
+
+func (z *zapLogger) levelEnabled(level LogLevel) bool {
+	return z.enabled(toZapLevel(level))
+}
+
+func toZapLevel(level LogLevel) zapcore.Level {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	case ErrorLevel:
+		return zapcore.ErrorLevel
+	case FatalLevel:
+		return zapcore.FatalLevel
+	}
+	return zapcore.InfoLevel
+}
+
+func (z *zapLogger) write(level zapcore.Level, msg string) {
+	if ce := z.logger.Check(level, msg); ce != nil {
+		ce.Write()
+	}
+}
+
+func (z *zapLogger) log(level zapcore.Level, args ...interface{}) {
+	if z.enabled(level) {
+		z.write(level, fmt.Sprint(args...))
+	}
+}
+
+func (z *zapLogger) logf(level zapcore.Level, format string, args ...interface{}) {
+	if z.enabled(level) {
+		z.write(level, fmt.Sprintf(format, args...))
+	}
+}
+
+// Info logs a message at level Info.
+func (z *zapLogger) Info(args ...interface{}) {
+	z.log(zapcore.InfoLevel, args...)
+}
+
+// Infof logs a message at level Info.
+func (z *zapLogger) Infof(format string, args ...interface{}) {
+	z.logf(zapcore.InfoLevel, format, args...)
+}
+
+// Debug logs a message at level Debug.
+func (z *zapLogger) Debug(args ...interface{}) {
+	z.log(zapcore.DebugLevel, args...)
+}
+
+// Debugf logs a message at level Debug.
+func (z *zapLogger) Debugf(format string, args ...interface{}) {
+	z.logf(zapcore.DebugLevel, format, args...)
+}
+
+// Warn logs a message at level Warn.
+func (z *zapLogger) Warn(args ...interface{}) {
+	z.log(zapcore.WarnLevel, args...)
+}
+
+// Warnf logs a message at level Warn.
+func (z *zapLogger) Warnf(format string, args ...interface{}) {
+	z.logf(zapcore.WarnLevel, format, args...)
+}
+
+// Error logs a message at level Error.
+func (z *zapLogger) Error(args ...interface{}) {
+	z.log(zapcore.ErrorLevel, args...)
+}
+
+// Errorf logs a message at level Error.
+func (z *zapLogger) Errorf(format string, args ...interface{}) {
+	z.logf(zapcore.ErrorLevel, format, args...)
+}
+
+// Fatal logs a message at level Fatal then the process will exit with status set to 1.
+func (z *zapLogger) Fatal(args ...interface{}) {
+	z.log(zapcore.FatalLevel, args...)
+}
+
+// Fatalf logs a message at level Fatal then the process will exit with status set to 1.
+func (z *zapLogger) Fatalf(format string, args ...interface{}) {
+	z.logf(zapcore.FatalLevel, format, args...)
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestZapLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	l := NewZapLogger("zap", core)
+
+	l.Debugf("dropped %d", 1)
+	l.WithFields(Fields{"product": "p1", "slot": 3}).
+		WithError(errors.New("boom")).
+		Warnf("slot %d offline", 3)
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 1)
+	e := entries[0]
+	assert.Equal(t, zapcore.WarnLevel, e.Level)
+	assert.Equal(t, "slot 3 offline", e.Message)
+
+	hostname, _ := os.Hostname()
+	fields := e.ContextMap()
+	assert.Equal(t, "zap", fields[logFieldScope])
+	assert.Equal(t, hostname, fields[logFieldInstance])
+	assert.Equal(t, LogTypeLog, fields[logFieldType])
+	assert.Equal(t, "p1", fields["product"])
+	assert.EqualValues(t, 3, fields["slot"])
+	assert.Equal(t, "boom", fields[logFieldError])
+}
+
+func TestZapLoggerLevelField(t *testing.T) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zapcore.InfoLevel)
+	NewZapLogger("zap", core).Warn("slot offline")
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "warn", entry[logFieldLevel])
+	assert.Equal(t, "zap", entry[logFieldScope])
+}
@@ -14,7 +14,7 @@ import (
 
 	"github.com/ngaut/zkhelper"
 
-	log "github.com/IceFireDB/kit/pkg/logger"
+	"github.com/IceFireDB/kit/pkg/models/client"
 	"github.com/juju/errors"
 )
 
@@ -27,6 +27,10 @@ const (
 	ACTION_TYPE_MULTI_SLOT_CHANGED   ActionType = "multi_slot_changed"
 	ACTION_TYPE_SLOT_MIGRATE         ActionType = "slot_migrate"
 	ACTION_TYPE_SLOT_PREMIGRATE      ActionType = "slot_premigrate"
+	ACTION_TYPE_SERVER_ADDED         ActionType = "server_added"
+	ACTION_TYPE_SERVER_REMOVED       ActionType = "server_removed"
+	ACTION_TYPE_SERVER_TYPE_CHANGED  ActionType = "server_type_changed"
+	ACTION_TYPE_PROXY_STATE_CHANGED  ActionType = "proxy_state_changed"
 )
 
 const (
@@ -34,39 +38,170 @@ const (
 	GC_TYPE_SEC
 )
 
+var (
+	ErrUnknownActionType    = errors.New("unknown action type")
+	ErrActionTargetMismatch = errors.New("action target does not match action type")
+)
+
 type Action struct {
-	Type      ActionType  `json:"type"`
-	Desc      string      `json:"desc"`
+	Type ActionType `json:"type"`
+	Desc string     `json:"desc"`
+	// Target holds the typed payload of Type, see NewActionTarget.
 	Target    interface{} `json:"target"`
 	Ts        string      `json:"ts"` // timestamp
 	Receivers []string    `json:"receivers"`
 }
 
-func (a *Action) Encode() []byte {
-	return jsonEncode(a)
+// NewActionTarget returns an empty payload of the concrete type carried by
+// actions of type t:
+//
+//	slot_changed, slot_migrate, slot_premigrate  *Slot
+//	multi_slot_changed                           *SlotMultiSetParam
+//	group_changed, group_remove                  *ServerGroup
+//	server_added, server_removed,
+//	server_type_changed                          *Server
+//	proxy_state_changed                          *ProxyInfo
+func NewActionTarget(t ActionType) (interface{}, error) {
+	switch t {
+	case ACTION_TYPE_SLOT_CHANGED, ACTION_TYPE_SLOT_MIGRATE, ACTION_TYPE_SLOT_PREMIGRATE:
+		return &Slot{}, nil
+	case ACTION_TYPE_MULTI_SLOT_CHANGED:
+		return &SlotMultiSetParam{}, nil
+	case ACTION_TYPE_SERVER_GROUP_CHANGED, ACTION_TYPE_SERVER_GROUP_REMOVE:
+		return &ServerGroup{}, nil
+	case ACTION_TYPE_SERVER_ADDED, ACTION_TYPE_SERVER_REMOVED, ACTION_TYPE_SERVER_TYPE_CHANGED:
+		return &Server{}, nil
+	case ACTION_TYPE_PROXY_STATE_CHANGED:
+		return &ProxyInfo{}, nil
+	}
+	return nil, errors.Errorf("%s: %s", ErrUnknownActionType, t)
+}
+
+// checkActionTarget accepts the payload type of t either by value or by pointer.
+func checkActionTarget(t ActionType, target interface{}) error {
+	switch t {
+	case ACTION_TYPE_SLOT_CHANGED, ACTION_TYPE_SLOT_MIGRATE, ACTION_TYPE_SLOT_PREMIGRATE:
+		switch target.(type) {
+		case Slot, *Slot:
+			return nil
+		}
+	case ACTION_TYPE_MULTI_SLOT_CHANGED:
+		switch target.(type) {
+		case SlotMultiSetParam, *SlotMultiSetParam:
+			return nil
+		}
+	case ACTION_TYPE_SERVER_GROUP_CHANGED, ACTION_TYPE_SERVER_GROUP_REMOVE:
+		switch target.(type) {
+		case ServerGroup, *ServerGroup:
+			return nil
+		}
+	case ACTION_TYPE_SERVER_ADDED, ACTION_TYPE_SERVER_REMOVED, ACTION_TYPE_SERVER_TYPE_CHANGED:
+		switch target.(type) {
+		case Server, *Server:
+			return nil
+		}
+	case ACTION_TYPE_PROXY_STATE_CHANGED:
+		switch target.(type) {
+		case ProxyInfo, *ProxyInfo:
+			return nil
+		}
+	default:
+		return errors.Errorf("%s: %s", ErrUnknownActionType, t)
+	}
+	return errors.Errorf("%s: %s with %T", ErrActionTargetMismatch, t, target)
+}
+
+func (a *Action) Encode() ([]byte, error) {
+	return encode(a)
+}
+
+// Decode fills a and converts its Target to the payload type of a.Type.
+// Targets of unknown types are left as decoded by the codec.
+func (a *Action) Decode(b []byte) error {
+	if err := decode(a, b); err != nil {
+		return err
+	}
+	target, err := NewActionTarget(a.Type)
+	if err != nil || a.Target == nil {
+		return nil
+	}
+	raw, err := json.Marshal(a.Target)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := json.Unmarshal(raw, target); err != nil {
+		return errors.Trace(err)
+	}
+	a.Target = target
+	return nil
+}
+
+// SlotTarget returns the payload of slot_changed, slot_migrate and slot_premigrate actions.
+func (a *Action) SlotTarget() (*Slot, error) {
+	if slot, ok := a.Target.(*Slot); ok {
+		return slot, nil
+	}
+	return nil, errors.Errorf("%s: %s with %T", ErrActionTargetMismatch, a.Type, a.Target)
+}
+
+// MultiSlotTarget returns the payload of multi_slot_changed actions.
+func (a *Action) MultiSlotTarget() (*SlotMultiSetParam, error) {
+	if param, ok := a.Target.(*SlotMultiSetParam); ok {
+		return param, nil
+	}
+	return nil, errors.Errorf("%s: %s with %T", ErrActionTargetMismatch, a.Type, a.Target)
+}
+
+// GroupTarget returns the payload of group_changed and group_remove actions.
+func (a *Action) GroupTarget() (*ServerGroup, error) {
+	if group, ok := a.Target.(*ServerGroup); ok {
+		return group, nil
+	}
+	return nil, errors.Errorf("%s: %s with %T", ErrActionTargetMismatch, a.Type, a.Target)
 }
 
-func (s *Store) GetActionWithSeq(seq string) (*Action, error) {
+// ServerTarget returns the payload of server_added, server_removed and server_type_changed actions.
+func (a *Action) ServerTarget() (*Server, error) {
+	if server, ok := a.Target.(*Server); ok {
+		return server, nil
+	}
+	return nil, errors.Errorf("%s: %s with %T", ErrActionTargetMismatch, a.Type, a.Target)
+}
+
+// ProxyTarget returns the payload of proxy_state_changed actions.
+func (a *Action) ProxyTarget() (*ProxyInfo, error) {
+	if proxy, ok := a.Target.(*ProxyInfo); ok {
+		return proxy, nil
+	}
+	return nil, errors.Errorf("%s: %s with %T", ErrActionTargetMismatch, a.Type, a.Target)
+}
+
+func (s *Store) GetActionWithSeq(seq string) (_ *Action, err error) {
+	span, cl := s.startSpan("GetActionWithSeq", client.Attr("seq", seq))
+	defer func() { span.End(err) }()
 	var act Action
-	data, err := s.client.Read(s.ActionPath(seq), true)
+	data, err := cl.Read(s.ActionPath(seq), true)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
-	if err := json.Unmarshal(data, &act); err != nil {
+	if err := act.Decode(data); err != nil {
 		return nil, errors.Trace(err)
 	}
 
 	return &act, nil
 }
 
+// GetActionObject decodes the action stored at seq into act.
+//
+// Deprecated: GetActionWithSeq returns the action with a typed Target.
 func (s *Store) GetActionObject(seq string, act interface{}) error {
 	data, err := s.client.Read(s.ActionPath(seq), true)
 	if err != nil {
 		return errors.Trace(err)
 	}
 
-	if err := json.Unmarshal(data, act); err != nil {
+	if err := decode(act, data); err != nil {
 		return errors.Trace(err)
 	}
 
@@ -125,8 +260,10 @@ var ErrReceiverTimeout = errors.New("receiver timeout")
 	return ErrReceiverTimeout
 }*/
 
-func (s *Store) GetActionSeqList() ([]string, error) {
-	nodes, err := s.client.List(GetWatchActionDir(s.product), true)
+func (s *Store) GetActionSeqList() (_ []string, err error) {
+	span, cl := s.startSpan("GetActionSeqList")
+	defer func() { span.End(err) }()
+	nodes, err := cl.List(s.ActionDir(), true)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -186,7 +323,7 @@ func ActionGC(zkConn zkhelper.Conn, productName string, gcType int, keep int) er
 			if err != nil {
 				return errors.Trace(err)
 			}
-			if err := json.Unmarshal(b, &act); err != nil {
+			if err := act.Decode(b); err != nil {
 				return errors.Trace(err)
 			}
 			log.Info(action, act.Ts)
@@ -220,7 +357,22 @@ func CreateActionRootPath(zkConn zkhelper.Conn, path string) error {
 	return nil
 }
 
-func (s *Store) NewAction(actionType ActionType, target interface{}, desc string, needConfirm bool) (err error) {
+// NewAction is EmitAction, needConfirm is ignored.
+//
+// Deprecated: nothing waits for proxies to confirm an action, use EmitAction.
+func (s *Store) NewAction(actionType ActionType, target interface{}, desc string, needConfirm bool) error {
+	return s.EmitAction(actionType, target, desc)
+}
+
+// EmitAction appends an action announcing target to the actions of the
+// product. Proxies pick it up by watching ActionDir, nothing waits for them
+// to acknowledge it.
+func (s *Store) EmitAction(actionType ActionType, target interface{}, desc string) (err error) {
+	span, _ := s.startSpan("EmitAction", client.Attr("action", string(actionType)))
+	defer func() { span.End(err) }()
+	if err := checkActionTarget(actionType, target); err != nil {
+		return err
+	}
 	ts := strconv.FormatInt(time.Now().Unix(), 10)
 
 	action := &Action{
@@ -254,13 +406,6 @@ func (s *Store) NewAction(actionType ActionType, target interface{}, desc string
 		return errors.Trace(err)
 	}
 
-	if needConfirm {
-		// todo sleep a small while to let others know
-		//if err := WaitForReceiver(zkConn, productName, actionCreated, proxies); err != nil {
-		//	return errors.Trace(err)
-		//}
-	}
-
 	return nil
 }
 
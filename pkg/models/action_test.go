@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/IceFireDB/kit/pkg/models/client"
+	memclient "github.com/IceFireDB/kit/pkg/models/client/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActionTarget(t *testing.T) {
+	assert.Nil(t, checkActionTarget(ACTION_TYPE_SLOT_MIGRATE, NewSlot(productName, 1)))
+	assert.Nil(t, checkActionTarget(ACTION_TYPE_MULTI_SLOT_CHANGED, SlotMultiSetParam{}))
+	assert.NotNil(t, checkActionTarget(ACTION_TYPE_SLOT_CHANGED, SlotMultiSetParam{}))
+	assert.NotNil(t, checkActionTarget(ActionType("unknown"), nil))
+
+	param := &SlotMultiSetParam{From: 0, To: 511, GroupId: 2, Status: SLOT_STATUS_ONLINE}
+	a := &Action{Type: ACTION_TYPE_MULTI_SLOT_CHANGED, Target: param}
+	b, err := a.Encode()
+	assert.Nil(t, err)
+
+	var got Action
+	assert.Nil(t, got.Decode(b))
+	target, err := got.MultiSlotTarget()
+	assert.Nil(t, err)
+	assert.Equal(t, param, target)
+	_, err = got.SlotTarget()
+	assert.NotNil(t, err)
+}
+
+type failCreateInOrder struct {
+	client.Client
+}
+
+func (failCreateInOrder) CreateInOrder(path string, data []byte) (string, error) {
+	return "", errors.New("create in order failed")
+}
+
+func TestServerActions(t *testing.T) {
+	c := memclient.New()
+	defer c.Close()
+	s := NewStore(c, productName)
+
+	// concurrent adds of the same server announce it once
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, s.UpdateServer(&Server{Addr: "127.0.0.1:6379", Type: ServerTypeLeader}))
+		}()
+	}
+	wg.Wait()
+	seqs, err := s.GetActionSeqList()
+	assert.Nil(t, err)
+	if assert.Len(t, seqs, 1) {
+		a, err := s.GetActionWithSeq(seqs[0])
+		assert.Nil(t, err)
+		assert.Equal(t, ACTION_TYPE_SERVER_ADDED, a.Type)
+	}
+
+	// the deprecated NewAction still emits
+	assert.Nil(t, s.NewAction(ACTION_TYPE_SLOT_CHANGED, NewSlot(productName, 0), "", true))
+	seqs, err = s.GetActionSeqList()
+	assert.Nil(t, err)
+	assert.Len(t, seqs, 2)
+
+	// a failed emit reports that the node was written
+	s = NewStore(failCreateInOrder{c}, productName)
+	err = s.UpdateServer(&Server{Addr: "127.0.0.1:6380", Type: ServerTypeLeader})
+	actionErr, ok := err.(*ActionError)
+	if assert.True(t, ok, "%v", err) {
+		assert.Equal(t, ACTION_TYPE_SERVER_ADDED, actionErr.Type)
+	}
+	server, err := s.GetServer("127.0.0.1:6380", true)
+	assert.Nil(t, err)
+	assert.NotNil(t, server)
+}
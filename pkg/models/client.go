@@ -4,23 +4,220 @@
 package models
 
 import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/IceFireDB/kit/pkg/logger"
 	"github.com/IceFireDB/kit/pkg/models/client"
 	"github.com/IceFireDB/kit/pkg/models/client/etcd"
 	etcdclient "github.com/IceFireDB/kit/pkg/models/client/etcdv2"
+	fsclient "github.com/IceFireDB/kit/pkg/models/client/fs"
+	memclient "github.com/IceFireDB/kit/pkg/models/client/mem"
 	zkclient "github.com/IceFireDB/kit/pkg/models/client/zk"
 	"github.com/pkg/errors"
 )
 
+// ClientOptions describes how to connect to a coordinator.
+type ClientOptions struct {
+	// Coordinator is one of zk, zookeeper, etcdv2, etcd, fs or mem.
+	Coordinator string
+	// AddrList is a comma separated list of coordinator addresses,
+	// or the data directory for fs.
+	AddrList string
+	// Auth is user:password, used as digest auth on zookeeper.
+	Auth    string
+	Timeout time.Duration
+
+	// TLS enables TLS when not nil.
+	TLS *client.TLSConfig
+	// Namespace is prepended to every path, see client.WithNamespace.
+	Namespace string
+	// Retry wraps the client with client.WithRetry when not nil. Nil
+	// classifiers are filled with the ones of the coordinator.
+	Retry *client.RetryPolicy
+	// Metrics records every request, retries included, when not nil.
+	Metrics *client.Metrics
+	// Tracer starts a span for every request, retries included, when not nil.
+	Tracer client.Tracer
+	// Logger receives the log of the client, the scope of the coordinator
+	// is used when nil.
+	Logger logger.Logger
+}
+
 func NewClient(coordinator string, addrlist string, auth string, timeout time.Duration) (client.Client, error) {
+	return NewClientWithOptions(&ClientOptions{
+		Coordinator: coordinator,
+		AddrList:    addrlist,
+		Auth:        auth,
+		Timeout:     timeout,
+	})
+}
+
+func NewClientWithOptions(opts *ClientOptions) (client.Client, error) {
+	c, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	// the wrappers below see the paths of the Store, not the namespaced ones
+	c = client.WithNamespace(c, opts.Namespace)
+	if opts.Metrics != nil {
+		c = client.WithMetrics(c, opts.Metrics, opts.Coordinator)
+	}
+	if opts.Tracer != nil {
+		c = client.WithTracing(c, opts.Tracer, opts.Coordinator)
+	}
+	if opts.Retry != nil {
+		policy := *opts.Retry
+		retryable, notSent := retryClassifiers(opts.Coordinator)
+		if policy.Retryable == nil {
+			policy.Retryable = retryable
+		}
+		if policy.NotSent == nil {
+			policy.NotSent = notSent
+		}
+		c = client.WithRetry(c, policy)
+	}
+	return c, nil
+}
+
+func retryClassifiers(coordinator string) (retryable, notSent func(error) bool) {
 	switch coordinator {
 	case "zk", "zookeeper":
-		return zkclient.New(addrlist, auth, timeout)
+		return zkclient.IsRetryable, zkclient.IsNotSent
 	case "etcdv2":
-		return etcdclient.New(addrlist, auth, timeout)
+		return etcdclient.IsRetryable, etcdclient.IsNotSent
 	case "etcd":
-		return etcd.New(addrlist, auth, timeout)
+		return etcd.IsRetryable, etcd.IsNotSent
+	}
+	return nil, nil
+}
+
+func newClient(opts *ClientOptions) (client.Client, error) {
+	switch opts.Coordinator {
+	case "zk", "zookeeper":
+		return zkclient.NewWithLogger(opts.AddrList, opts.Auth, opts.Timeout, opts.TLS, opts.Logger)
+	case "etcdv2":
+		return etcdclient.NewWithLogger(opts.AddrList, opts.Auth, opts.Timeout, opts.TLS, opts.Logger)
+	case "etcd":
+		return etcd.NewWithLogger(opts.AddrList, opts.Auth, opts.Timeout, opts.TLS, opts.Logger)
+	case "fs":
+		return fsclient.NewWithLogger(opts.AddrList, opts.Logger)
+	case "mem", "memory":
+		return memclient.New(), nil
+	}
+	return nil, errors.Errorf("invalid coordinator name = %s", opts.Coordinator)
+}
+
+// NewClientFromDSN is NewClientWithOptions for a DSN, see ParseDSN.
+func NewClientFromDSN(dsn string) (client.Client, error) {
+	opts, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientWithOptions(opts)
+}
+
+// ErrSASLNotSupported is returned for the SASL options of a DSN. The clients
+// authenticate with user:password (digest auth on zookeeper) or with the
+// client certificate of the tls options.
+var ErrSASLNotSupported = errors.New("sasl is not supported, use user:password@ or a tls client certificate")
+
+// ParseDSN parses a coordinator address of the form
+//
+//	coordinator://[user:password@]host1[,host2...][/namespace][?key=value...]
+//
+// e.g. etcd://user:pass@h1:2379,h2:2379/ns?timeout=5s. For fs the path is
+// the data directory (fs:///var/lib/icefire) and mem:// takes no address.
+// Recognized keys are timeout, namespace, tls, ca, cert, key, server_name
+// and insecure_skip_verify; setting any of the tls keys enables TLS, and
+// combining them with tls=false is an error. SASL is not supported, the
+// sasl, sasl_mechanism and user keys fail with ErrSASLNotSupported.
+func ParseDSN(dsn string) (*ClientOptions, error) {
+	split := strings.SplitN(dsn, "://", 2)
+	if len(split) != 2 || split[0] == "" {
+		return nil, errors.Errorf("invalid dsn = %s", dsn)
+	}
+	opts := &ClientOptions{Coordinator: split[0]}
+	rest := split[1]
+
+	var query string
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		rest, query = rest[:i], rest[i+1:]
+	}
+	var path string
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		rest, path = rest[:i], rest[i:]
+	}
+	if i := strings.LastIndexByte(rest, '@'); i >= 0 {
+		auth, err := url.PathUnescape(rest[:i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid dsn auth")
+		}
+		opts.Auth, rest = auth, rest[i+1:]
+	}
+	opts.AddrList = rest
+	if opts.Coordinator == "fs" {
+		opts.AddrList = path
+	} else if path != "/" {
+		opts.Namespace = path
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid dsn query")
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var tls client.TLSConfig
+	var secure, tlsSet, tlsOn bool
+	for _, key := range keys {
+		value := values.Get(key)
+		switch key {
+		case "timeout":
+			if opts.Timeout, err = time.ParseDuration(value); err != nil {
+				return nil, errors.Wrapf(err, "invalid dsn timeout")
+			}
+		case "namespace":
+			opts.Namespace = value
+		case "tls":
+			if tlsOn, err = strconv.ParseBool(value); err != nil {
+				return nil, errors.Wrapf(err, "invalid dsn tls")
+			}
+			tlsSet = true
+		case "ca":
+			tls.CAFile, secure = value, true
+		case "cert":
+			tls.CertFile, secure = value, true
+		case "key":
+			tls.KeyFile, secure = value, true
+		case "server_name":
+			tls.ServerName, secure = value, true
+		case "insecure_skip_verify":
+			if tls.InsecureSkipVerify, err = strconv.ParseBool(value); err != nil {
+				return nil, errors.Wrapf(err, "invalid dsn insecure_skip_verify")
+			}
+			secure = true
+		case "sasl", "sasl_mechanism", "user":
+			return nil, errors.Errorf("dsn option %s: %s", key, ErrSASLNotSupported)
+		default:
+			return nil, errors.Errorf("unknown dsn option = %s", key)
+		}
+	}
+	if tlsSet {
+		if !tlsOn && secure {
+			return nil, errors.Errorf("dsn tls=false conflicts with the tls options")
+		}
+		secure = tlsOn
+	}
+	if secure {
+		opts.TLS = &tls
 	}
-	return nil, errors.Errorf("invalid coordinator name = %s", coordinator)
+	return opts, nil
 }
@@ -50,3 +50,75 @@ type Client interface {
 
 	WatchInOrder(path string) (<-chan Event, []string, error)
 }
+
+// SessionNotifier is implemented by clients whose session with the
+// coordinator can be replaced, such as the zk client. The wrappers of this
+// package implement it by forwarding to the client they wrap.
+type SessionNotifier interface {
+	// SessionEvents reports EventSession every time the client moved to a
+	// new session.
+	SessionEvents() <-chan Event
+}
+
+// SessionEvents returns the session events of c, or nil when c has no
+// session to report on.
+func SessionEvents(c Client) <-chan Event {
+	if n, ok := c.(SessionNotifier); ok {
+		return n.SessionEvents()
+	}
+	return nil
+}
+
+// BulkReader is implemented by clients reading many nodes in fewer round
+// trips than one Read each, such as the backends of this package. The
+// wrappers of this package implement it by forwarding to the client they
+// wrap.
+type BulkReader interface {
+	// ReadMany reads all paths in as few round trips as the backend allows.
+	// The result is aligned with paths; missing nodes are nil unless must is set.
+	ReadMany(paths []string, must bool) ([][]byte, error)
+	// ListWithValues returns the children of path together with their data.
+	ListWithValues(path string, must bool) (map[string][]byte, error)
+}
+
+// ReadMany reads paths with c.ReadMany, or with one Read per path issued by
+// ParallelDo when c is not a BulkReader.
+func ReadMany(c Client, paths []string, must bool) ([][]byte, error) {
+	if r, ok := c.(BulkReader); ok {
+		return r.ReadMany(paths, must)
+	}
+	data := make([][]byte, len(paths))
+	err := ParallelDo(len(paths), func(i int) error {
+		b, err := c.Read(paths[i], must)
+		data[i] = b
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ListWithValues lists the children of path with their data using
+// c.ListWithValues, or List and ReadMany when c is not a BulkReader.
+// Children deleted in between are left out.
+func ListWithValues(c Client, path string, must bool) (map[string][]byte, error) {
+	if r, ok := c.(BulkReader); ok {
+		return r.ListWithValues(path, must)
+	}
+	paths, err := c.List(path, must)
+	if err != nil || paths == nil {
+		return nil, err
+	}
+	data, err := ReadMany(c, paths, false)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(paths))
+	for i, p := range paths {
+		if data[i] != nil {
+			values[p] = data[i]
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,63 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/IceFireDB/kit/pkg/models/client"
+	memclient "github.com/IceFireDB/kit/pkg/models/client/mem"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type sessionClient struct {
+	*memclient.Client
+	events chan client.Event
+}
+
+func (c *sessionClient) SessionEvents() <-chan client.Event {
+	return c.events
+}
+
+func TestSessionEvents(t *testing.T) {
+	m, err := client.NewMetrics(prometheus.NewRegistry())
+	assert.Nil(t, err)
+	raw := &sessionClient{memclient.New(), make(chan client.Event, 1)}
+	var c client.Client = raw
+	c = client.WithMetrics(c, m, "mem")
+	c = client.WithTracing(c, client.NewMemoryTracer(), "mem")
+	c = client.WithRetry(c, client.DefaultRetryPolicy)
+	c = client.WithNamespace(c, "/ns")
+
+	raw.events <- client.Event{Type: client.EventSession}
+	assert.Equal(t, client.Event{Type: client.EventSession}, <-client.SessionEvents(c))
+
+	assert.Nil(t, client.SessionEvents(memclient.New()))
+}
+
+// basicClient hides the BulkReader methods of the client it embeds.
+type basicClient struct {
+	client.Client
+}
+
+func TestBulkReaderFallback(t *testing.T) {
+	raw := memclient.New()
+	assert.Nil(t, raw.Create("/p/a", []byte("a")))
+	assert.Nil(t, raw.Create("/p/b", []byte("b")))
+	for _, c := range []client.Client{raw, basicClient{raw}, client.WithNamespace(basicClient{raw}, "/")} {
+		_, bulk := c.(client.BulkReader)
+		data, err := client.ReadMany(c, []string{"/p/a", "/p/missing", "/p/b"}, false)
+		assert.Nil(t, err, "bulk %v", bulk)
+		assert.Equal(t, [][]byte{[]byte("a"), nil, []byte("b")}, data)
+		_, err = client.ReadMany(c, []string{"/p/a", "/p/missing"}, true)
+		assert.True(t, client.Is(err, client.ErrNotFound), "bulk %v", bulk)
+
+		values, err := client.ListWithValues(c, "/p", true)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string][]byte{"/p/a": []byte("a"), "/p/b": []byte("b")}, values)
+		values, err = client.ListWithValues(c, "/missing", false)
+		assert.Nil(t, err)
+		assert.Nil(t, values)
+	}
+	_, ok := interface{}(basicClient{raw}).(client.BulkReader)
+	assert.False(t, ok)
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"errors"
+
+	cerrors "github.com/CodisLabs/codis/pkg/utils/errors"
+	"github.com/CodisLabs/codis/pkg/utils/trace"
+)
+
+// Every backend maps its own failures onto these kinds, test them with
+// errors.Is. Errors traced again by callers with the CodisLabs errors
+// package hide the kind, use Is for those.
+var (
+	ErrNotFound        = errors.New("node not found")
+	ErrExists          = errors.New("node already exists")
+	ErrVersionConflict = errors.New("node version conflict")
+	ErrClosed          = errors.New("use of closed client")
+	ErrTimeout         = errors.New("request timed out")
+)
+
+// Error is a backend error of a known Kind. It unwraps to the backend error,
+// so errors.Is matches both the kind and e.g. zk.ErrNoNode.
+type Error struct {
+	Kind  error
+	Err   error
+	Stack trace.Stack
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Wrap returns err as an Error of the given kind, or nil if err is nil.
+func Wrap(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := cerrors.Cause(err).(*Error); ok && e.Kind == kind {
+		return e
+	}
+	return &Error{
+		Kind:  kind,
+		Err:   cerrors.Cause(err),
+		Stack: trace.TraceN(1, 32),
+	}
+}
+
+// Is is errors.Is looking through CodisLabs traced errors.
+func Is(err, target error) bool {
+	return errors.Is(err, target) || errors.Is(cerrors.Cause(err), target)
+}
+
+// Cause returns the backend error behind err, looking through traced errors
+// and Error.
+func Cause(err error) error {
+	err = cerrors.Cause(err)
+	if e, ok := err.(*Error); ok {
+		return e.Err
+	}
+	return err
+}
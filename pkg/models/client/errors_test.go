@@ -0,0 +1,44 @@
+package client_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	cerrors "github.com/CodisLabs/codis/pkg/utils/errors"
+	"github.com/IceFireDB/kit/pkg/models/client"
+	fsclient "github.com/IceFireDB/kit/pkg/models/client/fs"
+	memclient "github.com/IceFireDB/kit/pkg/models/client/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+func testErrors(t *testing.T, c client.Client) {
+	_, err := c.Read("/missing", true)
+	assert.True(t, errors.Is(err, client.ErrNotFound))
+	assert.False(t, errors.Is(err, client.ErrExists))
+	assert.True(t, client.Is(cerrors.Trace(err), client.ErrNotFound))
+
+	assert.Nil(t, c.Create("/node", []byte("x")))
+	err = c.Create("/node", []byte("x"))
+	assert.True(t, errors.Is(err, client.ErrExists))
+
+	assert.Nil(t, c.Close())
+	_, err = c.Read("/node", false)
+	assert.True(t, errors.Is(err, client.ErrClosed))
+}
+
+func TestErrors(t *testing.T) {
+	testErrors(t, memclient.New())
+
+	dir, err := ioutil.TempDir("", "fsclient")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	c, err := fsclient.New(dir)
+	assert.Nil(t, err)
+	testErrors(t, c)
+
+	_, err = memclient.New().Read("/missing", true)
+	assert.True(t, errors.Is(err, memclient.ErrNotExist))
+	assert.Equal(t, memclient.ErrNotExist, client.Cause(err))
+}
@@ -2,6 +2,7 @@ package etcd
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,7 +10,7 @@ import (
 	"time"
 
 	"github.com/CodisLabs/codis/pkg/utils/errors"
-	log "github.com/IceFireDB/kit/pkg/logger"
+	"github.com/IceFireDB/kit/pkg/logger"
 	clientlocal "github.com/IceFireDB/kit/pkg/models/client"
 	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	"go.etcd.io/etcd/client/v3"
@@ -19,12 +20,18 @@ import (
 
 const MAX_TTL = 365 * 24 * 60 * 60 * time.Second
 
+// maxTxnOps matches the default --max-txn-ops of etcd servers.
+const maxTxnOps = 128
+
 var ErrClosedClient = errors.New("use of closed etcd client")
 
+var log logger.Logger = logger.NewLogger("etcdclient")
+
 var (
 	ErrNotDir   = errors.New("etcd: not a dir")
 	ErrNotFile  = errors.New("etcd: not a file")
 	ErrNotExist = errors.New("etcd: not exist")
+	ErrExist    = errors.New("etcd: node exists")
 )
 
 type Client struct {
@@ -33,17 +40,33 @@ type Client struct {
 
 	closed  bool
 	timeout time.Duration
-	lastKey int
+	log     logger.Logger
 
 	cancel  context.CancelFunc
 	context context.Context
 }
 
 func New(addrlist string, auth string, timeout time.Duration) (*Client, error) {
+	return NewWithTLS(addrlist, auth, timeout, nil)
+}
+
+// NewWithTLS connects over https when tlsConfig is not nil; endpoints given
+// with an explicit scheme are kept as they are.
+func NewWithTLS(addrlist string, auth string, timeout time.Duration, tlsConfig *clientlocal.TLSConfig) (*Client, error) {
+	return NewWithLogger(addrlist, auth, timeout, tlsConfig, nil)
+}
+
+// NewWithLogger is NewWithTLS writing the log of the client to l, or to the
+// "etcdclient" scope when l is nil.
+func NewWithLogger(addrlist string, auth string, timeout time.Duration, tlsConfig *clientlocal.TLSConfig, l logger.Logger) (*Client, error) {
+	scheme := "http://"
+	if tlsConfig != nil {
+		scheme = "https://"
+	}
 	endpoints := strings.Split(addrlist, ",")
 	for i, s := range endpoints {
-		if s != "" && !strings.HasPrefix(s, "http://") {
-			endpoints[i] = "http://" + s
+		if s != "" && !strings.Contains(s, "://") {
+			endpoints[i] = scheme + s
 		}
 	}
 	if timeout <= 0 {
@@ -55,6 +78,14 @@ func New(addrlist string, auth string, timeout time.Duration) (*Client, error) {
 		DialTimeout: 5 * time.Second,
 	}
 
+	if tlsConfig != nil {
+		tlsc, err := tlsConfig.ClientConfig()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		config.TLS = tlsc
+	}
+
 	if auth != "" {
 		split := strings.SplitN(auth, ":", 2)
 		if len(split) != 2 || split[0] == "" {
@@ -73,8 +104,11 @@ func New(addrlist string, auth string, timeout time.Duration) (*Client, error) {
 		return nil, errors.Trace(err)
 	}
 
+	if l == nil {
+		l = log
+	}
 	client := &Client{
-		client: cli, timeout: timeout,
+		client: cli, timeout: timeout, log: l,
 	}
 	client.context, client.cancel = context.WithCancel(context.Background())
 	return client, nil
@@ -131,6 +165,58 @@ func isErrNodeExists(err error) bool {
 	return false
 }
 
+// mapError gives err the client error kind of its etcd cause, if it has one.
+func mapError(err error) error {
+	cause := errors.Cause(err)
+	if _, ok := cause.(*clientlocal.Error); ok || cause == nil {
+		return cause
+	}
+	switch rpctypes.Error(cause) {
+	case rpctypes.ErrTimeout, rpctypes.ErrTimeoutDueToLeaderFail, rpctypes.ErrTimeoutDueToConnectionLost:
+		return clientlocal.Wrap(clientlocal.ErrTimeout, err)
+	}
+	if ev, ok := status.FromError(cause); ok && ev.Code() == codes.DeadlineExceeded {
+		return clientlocal.Wrap(clientlocal.ErrTimeout, err)
+	}
+	switch {
+	case stderrors.Is(cause, context.DeadlineExceeded):
+		return clientlocal.Wrap(clientlocal.ErrTimeout, err)
+	case cause == ErrClosedClient, stderrors.Is(cause, context.Canceled):
+		return clientlocal.Wrap(clientlocal.ErrClosed, err)
+	}
+	return errors.Trace(err)
+}
+
+// IsRetryable reports errors after which an idempotent request may succeed
+// when sent again: timeouts, leader changes and unavailable endpoints.
+func IsRetryable(err error) bool {
+	err = clientlocal.Cause(err)
+	switch {
+	case err == nil:
+		return false
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return true
+	}
+	switch rpctypes.Error(err) {
+	case rpctypes.ErrNoLeader, rpctypes.ErrLeaderChanged, rpctypes.ErrTimeout,
+		rpctypes.ErrTimeoutDueToLeaderFail, rpctypes.ErrTimeoutDueToConnectionLost,
+		rpctypes.ErrUnhealthy:
+		return true
+	}
+	if ev, ok := status.FromError(err); ok {
+		switch ev.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotSent reports errors returned before the request could be applied.
+func IsNotSent(err error) bool {
+	return rpctypes.Error(clientlocal.Cause(err)) == rpctypes.ErrNoLeader
+}
+
 func (c *Client) Mkdir(path string) error {
 	return nil
 	//c.Lock()
@@ -154,17 +240,25 @@ func (c *Client) Create(path string, data []byte) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd create node %s", path)
-	_, err := c.client.Put(cntx, path, string(data)) //&clientv3.OpOption{PrevExist: clientv3.PrevNoExist})
+	c.log.Debugf("etcd create node %s", path)
+	r, err := c.client.Txn(cntx).If(
+		clientv3.Compare(clientv3.CreateRevision(path), "=", 0),
+	).Then(
+		clientv3.OpPut(path, string(data)),
+	).Commit()
 	if err != nil {
-		log.Debugf("etcd create node %s failed: %s", path, err)
-		return errors.Trace(err)
+		c.log.Debugf("etcd create node %s failed: %s", path, err)
+		return mapError(err)
 	}
-	log.Debugf("etcd create OK")
+	if !r.Succeeded {
+		c.log.Debugf("etcd create node %s failed: exists", path)
+		return clientlocal.Wrap(clientlocal.ErrExists, ErrExist)
+	}
+	c.log.Debugf("etcd create OK")
 	return nil
 }
 
@@ -172,17 +266,17 @@ func (c *Client) Update(path string, data []byte) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd update node %s", path)
+	c.log.Debugf("etcd update node %s", path)
 	_, err := c.client.Put(cntx, path, string(data))
 	if err != nil {
-		log.Debugf("etcd update node %s failed: %s", path, err)
-		return errors.Trace(err)
+		c.log.Debugf("etcd update node %s failed: %s", path, err)
+		return mapError(err)
 	}
-	log.Debugf("etcd update OK")
+	c.log.Debugf("etcd update OK")
 	return nil
 }
 
@@ -190,17 +284,17 @@ func (c *Client) Delete(path string) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd delete node %s", path)
+	c.log.Debugf("etcd delete node %s", path)
 	res, err := c.client.Delete(cntx, path)
 	if err != nil {
-		log.Debugf("etcd delete node %s failed: %s", path, err)
-		return errors.Trace(err)
+		c.log.Debugf("etcd delete node %s failed: %s", path, err)
+		return mapError(err)
 	}
-	log.Debugf("etcd delete OK %d", res.Deleted)
+	c.log.Debugf("etcd delete OK %d", res.Deleted)
 	return nil
 }
 
@@ -208,17 +302,17 @@ func (c *Client) Read(path string, must bool) ([]byte, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
 	r, err := c.client.Get(cntx, path)
 	switch {
 	case err != nil:
-		log.Debugf("etcd read node %s failed: %s", path, err)
-		return nil, errors.Trace(err)
+		c.log.Debugf("etcd read node %s failed: %s", path, err)
+		return nil, mapError(err)
 	case r.Count > 1:
-		log.Debugf("etcd read node %s failed: not a file", path)
+		c.log.Debugf("etcd read node %s failed: not a file", path)
 		return nil, errors.Trace(ErrNotFile)
 	case r.Count == 1:
 		return r.Kvs[0].Value, nil
@@ -226,8 +320,8 @@ func (c *Client) Read(path string, must bool) ([]byte, error) {
 		if !must {
 			return nil, nil
 		}
-		log.Debugf("etcd read node %s failed: not a file", path)
-		return nil, errors.Trace(ErrNotFile)
+		c.log.Debugf("etcd read node %s failed: not exist", path)
+		return nil, clientlocal.Wrap(clientlocal.ErrNotFound, ErrNotExist)
 	}
 }
 
@@ -235,7 +329,7 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 	if path[len(path)-1] != '/' {
 		path += "/"
@@ -245,14 +339,14 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 	r, err := c.client.Get(cntx, path, clientv3.WithPrefix(), clientv3.WithKeysOnly())
 	switch {
 	case err != nil:
-		log.Debugf("etcd list node %s failed: %s", path, err)
-		return nil, errors.Trace(err)
+		c.log.Debugf("etcd list node %s failed: %s", path, err)
+		return nil, mapError(err)
 	case r.Count == 0:
 		if !must {
 			return nil, nil
 		}
-		log.Debugf("etcd list node %s failed: not a dir", path)
-		return nil, errors.Trace(ErrNotDir)
+		c.log.Debugf("etcd list node %s failed: not a dir", path)
+		return nil, clientlocal.Wrap(clientlocal.ErrNotFound, ErrNotDir)
 	default:
 		paths := make([]string, 0, r.Count)
 		for _, node := range r.Kvs {
@@ -262,67 +356,187 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 	}
 }
 
-// assume this is only used by cli, and cli operation is locked. So just not support concurrency create.
+func (c *Client) ReadMany(paths []string, must bool) ([][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, mapError(ErrClosedClient)
+	}
+	data := make([][]byte, len(paths))
+	for begin := 0; begin < len(paths); begin += maxTxnOps {
+		end := begin + maxTxnOps
+		if end > len(paths) {
+			end = len(paths)
+		}
+		ops := make([]clientv3.Op, 0, end-begin)
+		for _, path := range paths[begin:end] {
+			ops = append(ops, clientv3.OpGet(path))
+		}
+		cntx, cancel := c.newContext()
+		r, err := c.client.Txn(cntx).Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			c.log.Debugf("etcd read-many nodes failed: %s", err)
+			return nil, mapError(err)
+		}
+		for i, resp := range r.Responses {
+			rr := resp.GetResponseRange()
+			switch {
+			case rr != nil && len(rr.Kvs) == 1:
+				data[begin+i] = rr.Kvs[0].Value
+			case must:
+				c.log.Debugf("etcd read node %s failed: not exist", paths[begin+i])
+				return nil, clientlocal.Wrap(clientlocal.ErrNotFound, ErrNotExist)
+			}
+		}
+	}
+	return data, nil
+}
+
+func (c *Client) ListWithValues(path string, must bool) (map[string][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, mapError(ErrClosedClient)
+	}
+	if path[len(path)-1] != '/' {
+		path += "/"
+	}
+	cntx, cancel := c.newContext()
+	defer cancel()
+	r, err := c.client.Get(cntx, path, clientv3.WithPrefix())
+	switch {
+	case err != nil:
+		c.log.Debugf("etcd list-values node %s failed: %s", path, err)
+		return nil, mapError(err)
+	case r.Count == 0:
+		if !must {
+			return nil, nil
+		}
+		c.log.Debugf("etcd list-values node %s failed: not a dir", path)
+		return nil, clientlocal.Wrap(clientlocal.ErrNotFound, ErrNotDir)
+	default:
+		values := make(map[string][]byte, len(r.Kvs))
+		for _, node := range r.Kvs {
+			values[string(node.Key)] = node.Value
+		}
+		return values, nil
+	}
+}
+
+// CreateInOrder allocates the next sequence number of path from a counter
+// key stored next to it (path + ".seq") and writes both in one transaction
+// guarded by the counter's revision, so concurrent creators in any process
+// retry instead of reusing a number. When the next node already exists, the
+// counter lags behind nodes written without it and is moved past the last one.
 func (c *Client) CreateInOrder(path string, data []byte) (string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return "", errors.Trace(ErrClosedClient)
+		return "", mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd create node %s", path)
+	c.log.Debugf("etcd create node %s", path)
 	if path[len(path)-1] != '/' {
 		path += "/"
 	}
-	if c.lastKey == 0 {
-		getoptions := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithKeysOnly()}
-		getoptions = append(getoptions, clientv3.WithLastKey()...)
-		last, err := c.client.Get(cntx, path, getoptions...)
+	seqKey := strings.TrimSuffix(path, "/") + ".seq"
+	var scan bool
+	for {
+		cmp, last, err := c.lastSequence(cntx, path, seqKey, scan)
 		if err != nil {
-			log.Debugf("etcd get last node %s failed: %s", path, err)
-			return "", errors.Trace(err)
+			return "", err
 		}
-		if last.Count != 0 {
-			lastkey := last.Kvs[0].Key
-			paths := strings.Split(string(lastkey), "/")
-			c.lastKey, err = strconv.Atoi(paths[len(paths)-1])
-			if err != nil {
-				log.Debugf("etcd get last node %s parse key %s failed: %s", path, string(lastkey), err)
-				return "", errors.Trace(err)
-			}
+		next := last + 1
+		node := path + fmt.Sprintf("%06d", next)
+		r, err := c.client.Txn(cntx).If(
+			cmp, clientv3.Compare(clientv3.CreateRevision(node), "=", 0),
+		).Then(
+			clientv3.OpPut(seqKey, strconv.Itoa(next)),
+			clientv3.OpPut(node, string(data)),
+		).Else(
+			clientv3.OpGet(node, clientv3.WithKeysOnly()),
+		).Commit()
+		if err != nil {
+			c.log.Debugf("etcd create node %s failed: %s", node, err)
+			return "", mapError(err)
+		}
+		if r.Succeeded {
+			c.log.Debugf("etcd create OK")
+			return node, nil
 		}
+		scan = r.Responses[0].GetResponseRange().Count != 0
+		c.log.Debugf("etcd create node %s conflicted, retry", node)
 	}
-	c.lastKey++
-	key := fmt.Sprintf("%06d", c.lastKey)
-	path = path + key
+}
 
-	_, err := c.client.Put(cntx, path, string(data))
+// lastSequence returns the last allocated sequence number of dir together
+// with the comparison that holds as long as nobody allocates another one.
+// Without a counter, or with scan, the number is at least the one of the
+// last key of dir, so directories written before the counter existed, or
+// behind its back, are seeded from their nodes.
+func (c *Client) lastSequence(cntx context.Context, dir, seqKey string, scan bool) (clientv3.Cmp, int, error) {
+	r, err := c.client.Get(cntx, seqKey)
 	if err != nil {
-		log.Debugf("etcd create node %s failed: %s", path, err)
-		return "", errors.Trace(err)
+		c.log.Debugf("etcd get sequence %s failed: %s", seqKey, err)
+		return clientv3.Cmp{}, 0, mapError(err)
+	}
+	var cmp clientv3.Cmp
+	var last int
+	if r.Count != 0 {
+		kv := r.Kvs[0]
+		if last, err = strconv.Atoi(string(kv.Value)); err != nil {
+			c.log.Debugf("etcd get sequence %s parse value %s failed: %s", seqKey, string(kv.Value), err)
+			return clientv3.Cmp{}, 0, mapError(err)
+		}
+		cmp = clientv3.Compare(clientv3.ModRevision(seqKey), "=", kv.ModRevision)
+		if !scan {
+			return cmp, last, nil
+		}
+	} else {
+		cmp = clientv3.Compare(clientv3.CreateRevision(seqKey), "=", 0)
+	}
+
+	getoptions := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithKeysOnly()}
+	getoptions = append(getoptions, clientv3.WithLastKey()...)
+	r, err = c.client.Get(cntx, dir, getoptions...)
+	if err != nil {
+		c.log.Debugf("etcd get last node %s failed: %s", dir, err)
+		return clientv3.Cmp{}, 0, mapError(err)
+	}
+	if r.Count == 0 {
+		return cmp, last, nil
+	}
+	lastkey := string(r.Kvs[0].Key)
+	n, err := strconv.Atoi(lastkey[strings.LastIndex(lastkey, "/")+1:])
+	if err != nil {
+		c.log.Debugf("etcd get last node %s parse key %s failed: %s", dir, lastkey, err)
+		return clientv3.Cmp{}, 0, mapError(err)
+	}
+	if n > last {
+		last = n
 	}
-	log.Debugf("etcd create OK")
-	return path, nil
+	return cmp, last, nil
 }
 
 func (c *Client) WatchInOrder(path string) (<-chan clientlocal.Event, []string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, nil, errors.Trace(ErrClosedClient)
+		return nil, nil, mapError(ErrClosedClient)
 	}
 	if path[len(path)-1] != '/' {
 		path += "/"
 	}
-	log.Debugf("etcd watch-inorder node %s", path)
+	c.log.Debugf("etcd watch-inorder node %s", path)
 	cntx, cancel := c.newContext()
 	defer cancel()
 	r, err := c.client.Get(cntx, path, clientv3.WithPrefix(), clientv3.WithKeysOnly())
 	switch {
 	case err != nil:
-		log.Debugf("etcd watch-inorder node %s failed: %s", path, err)
-		return nil, nil, errors.Trace(err)
+		c.log.Debugf("etcd watch-inorder node %s failed: %s", path, err)
+		return nil, nil, mapError(err)
 	}
 	var paths []string
 	for _, node := range r.Kvs {
@@ -342,16 +556,16 @@ func (c *Client) WatchInOrder(path string) (<-chan clientlocal.Event, []string,
 			r, ok := <-watch
 			switch {
 			case !ok:
-				log.Debugf("etch watch-inorder node %s canceled", path)
+				c.log.Debugf("etch watch-inorder node %s canceled", path)
 				return
 			case !r.Created:
 				et = clientlocal.EventNodeChildrenChanged
-				log.Debugf("etcd watch-inorder node %s update", path)
+				c.log.Debugf("etcd watch-inorder node %s update", path)
 				return
 			}
-			log.Debugf("etch watch-inorder node %s ignore", path)
+			c.log.Debugf("etch watch-inorder node %s ignore", path)
 		}
 	}()
-	log.Debugf("etcd watch-inorder OK")
+	c.log.Debugf("etcd watch-inorder OK")
 	return signal, paths, nil
 }
@@ -0,0 +1,144 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	clientlocal "github.com/IceFireDB/kit/pkg/models/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+func freeURL(t *testing.T) url.URL {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer l.Close()
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
+
+// newTestServer starts an etcd server for the test and returns its client
+// address.
+func newTestServer(t *testing.T) string {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	peer, client := freeURL(t), freeURL(t)
+	cfg.LPUrls, cfg.APUrls = []url.URL{peer}, []url.URL{peer}
+	cfg.LCUrls, cfg.ACUrls = []url.URL{client}, []url.URL{client}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	require.Nil(t, err)
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd server not ready")
+	}
+	return client.Host
+}
+
+func newTestClient(t *testing.T, addr string) *Client {
+	c, err := New(addr, "", 5*time.Second)
+	require.Nil(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestCreateInOrder(t *testing.T) {
+	c := newTestClient(t, newTestServer(t))
+	put := func(key, value string) {
+		_, err := c.client.Put(context.Background(), key, value)
+		require.Nil(t, err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		setup func(dir string)
+		want  string
+	}{
+		{name: "empty", want: "000001"},
+		{name: "counter", setup: func(dir string) {
+			put(dir+"/000001", "")
+			put(dir+".seq", "1")
+		}, want: "000002"},
+		{name: "written before the counter", setup: func(dir string) {
+			put(dir+"/000001", "")
+			put(dir+"/000002", "")
+		}, want: "000003"},
+		{name: "counter behind the nodes", setup: func(dir string) {
+			put(dir+"/000001", "")
+			put(dir+"/000002", "")
+			put(dir+".seq", "0")
+		}, want: "000003"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := "/" + tt.name
+			if tt.setup != nil {
+				tt.setup(dir)
+			}
+			node, err := c.CreateInOrder(dir, []byte("data"))
+			assert.Nil(t, err)
+			assert.Equal(t, dir+"/"+tt.want, node)
+
+			data, err := c.Read(node, true)
+			assert.Nil(t, err)
+			assert.Equal(t, []byte("data"), data)
+		})
+	}
+}
+
+func TestCreateInOrderConcurrent(t *testing.T) {
+	addr := newTestServer(t)
+	clients := []*Client{newTestClient(t, addr), newTestClient(t, addr)}
+
+	var mu sync.Mutex
+	nodes := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(c *Client, i int) {
+			defer wg.Done()
+			node, err := c.CreateInOrder("/actions", []byte(fmt.Sprint(i)))
+			assert.Nil(t, err)
+			mu.Lock()
+			defer mu.Unlock()
+			assert.False(t, nodes[node], node)
+			nodes[node] = true
+		}(clients[i%2], i)
+	}
+	wg.Wait()
+
+	paths, err := clients[0].List("/actions", true)
+	assert.Nil(t, err)
+	assert.Len(t, paths, 20)
+}
+
+func TestErrors(t *testing.T) {
+	c := newTestClient(t, newTestServer(t))
+
+	assert.Nil(t, c.Create("/lock", []byte("1")))
+	err := c.Create("/lock", []byte("2"))
+	assert.True(t, errors.Is(err, clientlocal.ErrExists), "%v", err)
+	data, err := c.Read("/lock", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("1"), data)
+
+	_, err = c.Read("/missing", true)
+	assert.True(t, errors.Is(err, clientlocal.ErrNotFound), "%v", err)
+	_, err = c.List("/missing", true)
+	assert.True(t, errors.Is(err, clientlocal.ErrNotFound), "%v", err)
+	_, err = c.ReadMany([]string{"/lock", "/missing"}, true)
+	assert.True(t, errors.Is(err, clientlocal.ErrNotFound), "%v", err)
+
+	assert.Nil(t, c.Close())
+	err = c.Update("/lock", nil)
+	assert.True(t, errors.Is(err, clientlocal.ErrClosed), "%v", err)
+}
@@ -4,12 +4,14 @@
 package etcdclient
 
 import (
+	"net"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/CodisLabs/codis/pkg/utils/errors"
-	"github.com/CodisLabs/codis/pkg/utils/log"
+	"github.com/IceFireDB/kit/pkg/logger"
 	clientlocal "github.com/IceFireDB/kit/pkg/models/client"
 	client "go.etcd.io/etcd/client/v2"
 	"golang.org/x/net/context"
@@ -19,6 +21,8 @@ const MAX_TTL = 365 * 24 * 60 * 60 * time.Second
 
 var ErrClosedClient = errors.New("use of closed etcd client")
 
+var log logger.Logger = logger.NewLogger("etcdv2client")
+
 var (
 	ErrNotDir  = errors.New("etcd: not a dir")
 	ErrNotFile = errors.New("etcd: not a file")
@@ -30,16 +34,33 @@ type Client struct {
 
 	closed  bool
 	timeout time.Duration
+	log     logger.Logger
 
 	cancel  context.CancelFunc
 	context context.Context
 }
 
 func New(addrlist string, auth string, timeout time.Duration) (*Client, error) {
+	return NewWithTLS(addrlist, auth, timeout, nil)
+}
+
+// NewWithTLS connects over https when tlsConfig is not nil; endpoints given
+// with an explicit scheme are kept as they are.
+func NewWithTLS(addrlist string, auth string, timeout time.Duration, tlsConfig *clientlocal.TLSConfig) (*Client, error) {
+	return NewWithLogger(addrlist, auth, timeout, tlsConfig, nil)
+}
+
+// NewWithLogger is NewWithTLS writing the log of the client to l, or to the
+// "etcdv2client" scope when l is nil.
+func NewWithLogger(addrlist string, auth string, timeout time.Duration, tlsConfig *clientlocal.TLSConfig, l logger.Logger) (*Client, error) {
+	scheme := "http://"
+	if tlsConfig != nil {
+		scheme = "https://"
+	}
 	endpoints := strings.Split(addrlist, ",")
 	for i, s := range endpoints {
-		if s != "" && !strings.HasPrefix(s, "http://") {
-			endpoints[i] = "http://" + s
+		if s != "" && !strings.Contains(s, "://") {
+			endpoints[i] = scheme + s
 		}
 	}
 	if timeout <= 0 {
@@ -51,6 +72,19 @@ func New(addrlist string, auth string, timeout time.Duration) (*Client, error) {
 		HeaderTimeoutPerRequest: time.Second * 5,
 	}
 
+	if tlsConfig != nil {
+		tlsc, err := tlsConfig.ClientConfig()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		config.Transport = &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			TLSClientConfig:     tlsc,
+		}
+	}
+
 	if auth != "" {
 		split := strings.SplitN(auth, ":", 2)
 		if len(split) != 2 || split[0] == "" {
@@ -65,8 +99,11 @@ func New(addrlist string, auth string, timeout time.Duration) (*Client, error) {
 		return nil, errors.Trace(err)
 	}
 
+	if l == nil {
+		l = log
+	}
 	client := &Client{
-		kapi: client.NewKeysAPI(c), timeout: timeout,
+		kapi: client.NewKeysAPI(c), timeout: timeout, log: l,
 	}
 	client.context, client.cancel = context.WithCancel(context.Background())
 	return client, nil
@@ -105,21 +142,70 @@ func isErrNodeExists(err error) bool {
 	return false
 }
 
+// mapError gives err the client error kind of its etcd cause, if it has one.
+func mapError(err error) error {
+	cause := errors.Cause(err)
+	if _, ok := cause.(*clientlocal.Error); ok || cause == nil {
+		return cause
+	}
+	if e, ok := cause.(client.Error); ok {
+		switch e.Code {
+		case client.ErrorCodeKeyNotFound:
+			return clientlocal.Wrap(clientlocal.ErrNotFound, err)
+		case client.ErrorCodeNodeExist:
+			return clientlocal.Wrap(clientlocal.ErrExists, err)
+		case client.ErrorCodeTestFailed:
+			return clientlocal.Wrap(clientlocal.ErrVersionConflict, err)
+		}
+	}
+	switch cause {
+	case context.DeadlineExceeded:
+		return clientlocal.Wrap(clientlocal.ErrTimeout, err)
+	case ErrClosedClient, context.Canceled:
+		return clientlocal.Wrap(clientlocal.ErrClosed, err)
+	}
+	return errors.Trace(err)
+}
+
+// IsRetryable reports errors after which an idempotent request may succeed
+// when sent again: timeouts, leader elections and unreachable members.
+func IsRetryable(err error) bool {
+	err = clientlocal.Cause(err)
+	switch e := err.(type) {
+	case nil:
+		return false
+	case client.Error:
+		return e.Code == client.ErrorCodeRaftInternal || e.Code == client.ErrorCodeLeaderElect
+	case *client.ClusterError:
+		return true
+	}
+	return err == context.DeadlineExceeded || err == client.ErrClusterUnavailable
+}
+
+// IsNotSent reports errors returned before the request could be applied.
+func IsNotSent(err error) bool {
+	switch clientlocal.Cause(err) {
+	case client.ErrNoEndpoints, client.ErrClusterUnavailable:
+		return true
+	}
+	return false
+}
+
 func (c *Client) Mkdir(path string) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
-	log.Debugf("etcd mkdir node %s", path)
+	c.log.Debugf("etcd mkdir node %s", path)
 	cntx, cancel := c.newContext()
 	defer cancel()
 	_, err := c.kapi.Set(cntx, path, "", &client.SetOptions{Dir: true, PrevExist: client.PrevNoExist})
 	if err != nil && !isErrNodeExists(err) {
-		log.Debugf("etcd mkdir node %s failed: %s", path, err)
-		return errors.Trace(err)
+		c.log.Debugf("etcd mkdir node %s failed: %s", path, err)
+		return mapError(err)
 	}
-	log.Debugf("etcd mkdir OK")
+	c.log.Debugf("etcd mkdir OK")
 	return nil
 }
 
@@ -127,17 +213,17 @@ func (c *Client) Create(path string, data []byte) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd create node %s", path)
+	c.log.Debugf("etcd create node %s", path)
 	_, err := c.kapi.Set(cntx, path, string(data), &client.SetOptions{PrevExist: client.PrevNoExist})
 	if err != nil {
-		log.Debugf("etcd create node %s failed: %s", path, err)
-		return errors.Trace(err)
+		c.log.Debugf("etcd create node %s failed: %s", path, err)
+		return mapError(err)
 	}
-	log.Debugf("etcd create OK")
+	c.log.Debugf("etcd create OK")
 	return nil
 }
 
@@ -145,17 +231,17 @@ func (c *Client) Update(path string, data []byte) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd update node %s", path)
+	c.log.Debugf("etcd update node %s", path)
 	_, err := c.kapi.Set(cntx, path, string(data), &client.SetOptions{PrevExist: client.PrevIgnore})
 	if err != nil {
-		log.Debugf("etcd update node %s failed: %s", path, err)
-		return errors.Trace(err)
+		c.log.Debugf("etcd update node %s failed: %s", path, err)
+		return mapError(err)
 	}
-	log.Debugf("etcd update OK")
+	c.log.Debugf("etcd update OK")
 	return nil
 }
 
@@ -163,17 +249,18 @@ func (c *Client) Delete(path string) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd delete node %s", path)
-	_, err := c.kapi.Delete(cntx, path, nil)
+	c.log.Debugf("etcd delete node %s", path)
+	// Dir allows removing emptied directories as well as plain keys
+	_, err := c.kapi.Delete(cntx, path, &client.DeleteOptions{Dir: true})
 	if err != nil && !isErrNoNode(err) {
-		log.Debugf("etcd delete node %s failed: %s", path, err)
-		return errors.Trace(err)
+		c.log.Debugf("etcd delete node %s failed: %s", path, err)
+		return mapError(err)
 	}
-	log.Debugf("etcd delete OK")
+	c.log.Debugf("etcd delete OK")
 	return nil
 }
 
@@ -181,7 +268,7 @@ func (c *Client) Read(path string, must bool) ([]byte, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
@@ -191,12 +278,12 @@ func (c *Client) Read(path string, must bool) ([]byte, error) {
 		if isErrNoNode(err) && !must {
 			return nil, nil
 		}
-		log.Debugf("etcd read node %s failed: %s", path, err)
-		return nil, errors.Trace(err)
+		c.log.Debugf("etcd read node %s failed: %s", path, err)
+		return nil, mapError(err)
 	case !r.Node.Dir:
 		return []byte(r.Node.Value), nil
 	default:
-		log.Debugf("etcd read node %s failed: not a file", path)
+		c.log.Debugf("etcd read node %s failed: not a file", path)
 		return nil, errors.Trace(ErrNotFile)
 	}
 }
@@ -205,7 +292,7 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
@@ -215,10 +302,10 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 		if isErrNoNode(err) && !must {
 			return nil, nil
 		}
-		log.Debugf("etcd list node %s failed: %s", path, err)
-		return nil, errors.Trace(err)
+		c.log.Debugf("etcd list node %s failed: %s", path, err)
+		return nil, mapError(err)
 	case !r.Node.Dir:
-		log.Debugf("etcd list node %s failed: not a dir", path)
+		c.log.Debugf("etcd list node %s failed: not a dir", path)
 		return nil, errors.Trace(ErrNotDir)
 	default:
 		var paths []string
@@ -229,21 +316,83 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 	}
 }
 
+func (c *Client) ReadMany(paths []string, must bool) ([][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, mapError(ErrClosedClient)
+	}
+	data := make([][]byte, len(paths))
+	err := clientlocal.ParallelDo(len(paths), func(i int) error {
+		cntx, cancel := c.newContext()
+		defer cancel()
+		r, err := c.kapi.Get(cntx, paths[i], &client.GetOptions{Quorum: true})
+		switch {
+		case err != nil:
+			if isErrNoNode(err) && !must {
+				return nil
+			}
+			c.log.Debugf("etcd read node %s failed: %s", paths[i], err)
+			return mapError(err)
+		case !r.Node.Dir:
+			data[i] = []byte(r.Node.Value)
+			return nil
+		default:
+			c.log.Debugf("etcd read node %s failed: not a file", paths[i])
+			return errors.Trace(ErrNotFile)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *Client) ListWithValues(path string, must bool) (map[string][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, mapError(ErrClosedClient)
+	}
+	cntx, cancel := c.newContext()
+	defer cancel()
+	r, err := c.kapi.Get(cntx, path, &client.GetOptions{Quorum: true})
+	switch {
+	case err != nil:
+		if isErrNoNode(err) && !must {
+			return nil, nil
+		}
+		c.log.Debugf("etcd list-values node %s failed: %s", path, err)
+		return nil, mapError(err)
+	case !r.Node.Dir:
+		c.log.Debugf("etcd list-values node %s failed: not a dir", path)
+		return nil, errors.Trace(ErrNotDir)
+	default:
+		values := make(map[string][]byte, len(r.Node.Nodes))
+		for _, node := range r.Node.Nodes {
+			if !node.Dir {
+				values[node.Key] = []byte(node.Value)
+			}
+		}
+		return values, nil
+	}
+}
+
 func (c *Client) CreateInOrder(path string, data []byte) (string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return "", errors.Trace(ErrClosedClient)
+		return "", mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd create node %s", path)
+	c.log.Debugf("etcd create node %s", path)
 	resp, err := c.kapi.CreateInOrder(cntx, path, string(data), &client.CreateInOrderOptions{TTL: MAX_TTL})
 	if err != nil {
-		log.Debugf("etcd create node %s failed: %s", path, err)
-		return "", errors.Trace(err)
+		c.log.Debugf("etcd create node %s failed: %s", path, err)
+		return "", mapError(err)
 	}
-	log.Debugf("etcd create OK")
+	c.log.Debugf("etcd create OK")
 	return resp.Node.Key, nil
 }
 
@@ -251,17 +400,17 @@ func (c *Client) CreateEphemeral(path string, data []byte) (<-chan struct{}, err
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd create-ephemeral node %s", path)
+	c.log.Debugf("etcd create-ephemeral node %s", path)
 	_, err := c.kapi.Set(cntx, path, string(data), &client.SetOptions{PrevExist: client.PrevNoExist, TTL: c.timeout})
 	if err != nil {
-		log.Debugf("etcd create-ephemeral node %s failed: %s", path, err)
-		return nil, errors.Trace(err)
+		c.log.Debugf("etcd create-ephemeral node %s failed: %s", path, err)
+		return nil, mapError(err)
 	}
-	log.Debugf("etcd create-ephemeral OK")
+	c.log.Debugf("etcd create-ephemeral OK")
 	return runRefreshEphemeral(c, path), nil
 }
 
@@ -269,18 +418,18 @@ func (c *Client) CreateEphemeralInOrder(path string, data []byte) (<-chan struct
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, "", errors.Trace(ErrClosedClient)
+		return nil, "", mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd create-ephemeral-inorder node %s", path)
+	c.log.Debugf("etcd create-ephemeral-inorder node %s", path)
 	r, err := c.kapi.CreateInOrder(cntx, path, string(data), &client.CreateInOrderOptions{TTL: c.timeout})
 	if err != nil {
-		log.Debugf("etcd create-ephemeral-inorder node %s failed: %s", path, err)
-		return nil, "", errors.Trace(err)
+		c.log.Debugf("etcd create-ephemeral-inorder node %s failed: %s", path, err)
+		return nil, "", mapError(err)
 	}
 	node := r.Node.Key
-	log.Debugf("etcd create-ephemeral-inorder OK, node = %s", node)
+	c.log.Debugf("etcd create-ephemeral-inorder OK, node = %s", node)
 	return runRefreshEphemeral(c, node), node, nil
 }
 
@@ -303,17 +452,17 @@ func (c *Client) RefreshEphemeral(path string) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 	cntx, cancel := c.newContext()
 	defer cancel()
-	log.Debugf("etcd refresh-ephemeral node %s", path)
+	c.log.Debugf("etcd refresh-ephemeral node %s", path)
 	_, err := c.kapi.Set(cntx, path, "", &client.SetOptions{PrevExist: client.PrevExist, Refresh: true, TTL: c.timeout})
 	if err != nil {
-		log.Debugf("etcd refresh-ephemeral node %s failed: %s", path, err)
-		return errors.Trace(err)
+		c.log.Debugf("etcd refresh-ephemeral node %s failed: %s", path, err)
+		return mapError(err)
 	}
-	log.Debugf("etcd refresh-ephemeral OK")
+	c.log.Debugf("etcd refresh-ephemeral OK")
 	return nil
 }
 
@@ -324,18 +473,18 @@ func (c *Client) WatchInOrder(path string) (<-chan clientlocal.Event, []string,
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, nil, errors.Trace(ErrClosedClient)
+		return nil, nil, mapError(ErrClosedClient)
 	}
-	log.Debugf("etcd watch-inorder node %s", path)
+	c.log.Debugf("etcd watch-inorder node %s", path)
 	cntx, cancel := c.newContext()
 	defer cancel()
 	r, err := c.kapi.Get(cntx, path, &client.GetOptions{Quorum: true, Sort: true})
 	switch {
 	case err != nil:
-		log.Debugf("etcd watch-inorder node %s failed: %s", path, err)
-		return nil, nil, errors.Trace(err)
+		c.log.Debugf("etcd watch-inorder node %s failed: %s", path, err)
+		return nil, nil, mapError(err)
 	case !r.Node.Dir:
-		log.Debugf("etcd watch-inorder node %s failed: not a dir", path)
+		c.log.Debugf("etcd watch-inorder node %s failed: not a dir", path)
 		return nil, nil, errors.Trace(ErrNotDir)
 	}
 	index := r.Index
@@ -355,17 +504,17 @@ func (c *Client) WatchInOrder(path string) (<-chan clientlocal.Event, []string,
 			r, err = watch.Next(c.context)
 			switch {
 			case err != nil:
-				log.Debugf("etch watch-inorder node %s failed: %s", path, err)
+				c.log.Debugf("etch watch-inorder node %s failed: %s", path, err)
 				return
 			case r.Action != "get":
-				log.Debugf("etcd watch-inorder node %s update", path)
+				c.log.Debugf("etcd watch-inorder node %s update", path)
 				return
 			}
-			log.Debugf("etch watch-inorder node %s ignore", path)
+			c.log.Debugf("etch watch-inorder node %s ignore", path)
 		}
 
 	}()
-	log.Debugf("etcd watch-inorder OK")
+	c.log.Debugf("etcd watch-inorder OK")
 	return signal, paths, nil
 }
 
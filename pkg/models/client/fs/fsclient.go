@@ -10,16 +10,21 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/CodisLabs/codis/pkg/utils/errors"
-	"github.com/CodisLabs/codis/pkg/utils/log"
+	"github.com/IceFireDB/kit/pkg/logger"
+	"github.com/IceFireDB/kit/pkg/models/client"
 )
 
 var ErrClosedClient = errors.New("use of closed fs client")
 
+var log logger.Logger = logger.NewLogger("fsclient")
+
 type Client struct {
 	sync.Mutex
 
@@ -30,9 +35,20 @@ type Client struct {
 
 	lockfd *os.File
 	closed bool
+
+	log logger.Logger
 }
 
 func New(dir string) (*Client, error) {
+	return NewWithLogger(dir, nil)
+}
+
+// NewWithLogger is New writing the log of the client to l, or to the
+// "fsclient" scope when l is nil.
+func NewWithLogger(dir string, l logger.Logger) (*Client, error) {
+	if l == nil {
+		l = log
+	}
 	fullpath, err := filepath.Abs(dir)
 	if err != nil {
 		return nil, errors.Trace(err)
@@ -42,6 +58,7 @@ func New(dir string) (*Client, error) {
 		DataDir:  filepath.Join(fullpath, "data"),
 		TempDir:  filepath.Join(fullpath, "temp"),
 		LockFile: filepath.Join(fullpath, "data.lck"),
+		log:      l,
 	}, nil
 }
 
@@ -85,11 +102,11 @@ func (c *Client) lockFs() error {
 	}
 	b, err := json.MarshalIndent(data, "", "    ")
 	if err != nil {
-		log.WarnErrorf(err, "fsclient - lock encode json failed")
+		c.log.WithError(err).Warnf("fsclient - lock encode json failed")
 	} else if err := f.Truncate(0); err != nil {
-		log.WarnErrorf(err, "fsclient - lock truncate failed")
+		c.log.WithError(err).Warnf("fsclient - lock truncate failed")
 	} else if _, err := f.Write(b); err != nil {
-		log.WarnErrorf(err, "fsclient - lock write failed")
+		c.log.WithError(err).Warnf("fsclient - lock write failed")
 	}
 	c.lockfd = f
 	return nil
@@ -97,20 +114,21 @@ func (c *Client) lockFs() error {
 
 func (c *Client) unlockFs() {
 	if c.lockfd == nil {
-		log.Panicf("unlock again")
+		c.log.Errorf("fsclient - unlock again")
+		panic("unlock again")
 	}
 	var f = c.lockfd
 	if err := f.Truncate(0); err != nil {
-		log.WarnErrorf(err, "fsclient - unlock truncate failed")
+		c.log.WithError(err).Warnf("fsclient - unlock truncate failed")
 	}
 	defer func() {
 		if err := f.Close(); err != nil {
-			log.WarnErrorf(err, "fsclient - unlock close failed")
+			c.log.WithError(err).Warnf("fsclient - unlock close failed")
 		}
 	}()
 
 	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
-		log.ErrorErrorf(err, "fsclient - unlock flock failed")
+		c.log.WithError(err).Errorf("fsclient - unlock flock failed")
 	}
 	c.lockfd = nil
 }
@@ -141,7 +159,7 @@ func (c *Client) writeFile(realpath string, data []byte, noexists bool) error {
 	if noexists {
 		_, err := os.Stat(realpath)
 		if err == nil {
-			return errors.Errorf("file already exists")
+			return client.Wrap(client.ErrExists, errors.Errorf("file already exists"))
 		} else if !os.IsNotExist(err) {
 			return errors.Trace(err)
 		}
@@ -176,11 +194,28 @@ func (c *Client) writeFile(realpath string, data []byte, noexists bool) error {
 	return nil
 }
 
+// mapError gives err the client error kind of its cause, if it has one.
+func mapError(err error) error {
+	cause := errors.Cause(err)
+	if _, ok := cause.(*client.Error); ok || cause == nil {
+		return cause
+	}
+	switch {
+	case cause == ErrClosedClient:
+		return client.Wrap(client.ErrClosed, err)
+	case os.IsNotExist(cause):
+		return client.Wrap(client.ErrNotFound, err)
+	case os.IsExist(cause):
+		return client.Wrap(client.ErrExists, err)
+	}
+	return errors.Trace(err)
+}
+
 func (c *Client) Create(path string, data []byte) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 
 	if err := c.lockFs(); err != nil {
@@ -189,10 +224,10 @@ func (c *Client) Create(path string, data []byte) error {
 	defer c.unlockFs()
 
 	if err := c.writeFile(c.realpath(path), data, true); err != nil {
-		log.Warnf("fsclient - create %s failed", path)
+		c.log.Warnf("fsclient - create %s failed", path)
 		return err
 	} else {
-		log.Infof("fsclient - create %s OK", path)
+		c.log.Infof("fsclient - create %s OK", path)
 		return nil
 	}
 }
@@ -201,7 +236,7 @@ func (c *Client) Update(path string, data []byte) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 
 	if err := c.lockFs(); err != nil {
@@ -210,10 +245,10 @@ func (c *Client) Update(path string, data []byte) error {
 	defer c.unlockFs()
 
 	if err := c.writeFile(c.realpath(path), data, false); err != nil {
-		log.Warnf("fsclient - update %s failed", path)
+		c.log.Warnf("fsclient - update %s failed", path)
 		return err
 	} else {
-		log.Infof("fsclient - update %s OK", path)
+		c.log.Infof("fsclient - update %s OK", path)
 		return nil
 	}
 }
@@ -222,7 +257,7 @@ func (c *Client) Delete(path string) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 
 	if err := c.lockFs(); err != nil {
@@ -231,10 +266,10 @@ func (c *Client) Delete(path string) error {
 	defer c.unlockFs()
 
 	if err := os.RemoveAll(c.realpath(path)); err != nil {
-		log.Warnf("fsclient - delete %s failed", path)
-		return errors.Trace(err)
+		c.log.Warnf("fsclient - delete %s failed", path)
+		return mapError(err)
 	} else {
-		log.Infof("fsclient - delete %s OK", path)
+		c.log.Infof("fsclient - delete %s OK", path)
 		return nil
 	}
 }
@@ -243,7 +278,7 @@ func (c *Client) Read(path string, must bool) ([]byte, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 
 	if err := c.lockFs(); err != nil {
@@ -256,7 +291,7 @@ func (c *Client) Read(path string, must bool) ([]byte, error) {
 		_, err := os.Stat(realpath)
 		if err != nil {
 			if !os.IsNotExist(err) {
-				return nil, errors.Trace(err)
+				return nil, mapError(err)
 			}
 			return nil, nil
 		}
@@ -264,8 +299,8 @@ func (c *Client) Read(path string, must bool) ([]byte, error) {
 
 	b, err := ioutil.ReadFile(realpath)
 	if err != nil {
-		log.Warnf("fsclient - read %s failed", path)
-		return nil, errors.Trace(err)
+		c.log.Warnf("fsclient - read %s failed", path)
+		return nil, mapError(err)
 	}
 	return b, nil
 }
@@ -274,7 +309,7 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 
 	if err := c.lockFs(); err != nil {
@@ -287,7 +322,7 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 		_, err := os.Stat(realpath)
 		if err != nil {
 			if !os.IsNotExist(err) {
-				return nil, errors.Trace(err)
+				return nil, mapError(err)
 			}
 			return nil, nil
 		}
@@ -295,15 +330,15 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 
 	f, err := os.Open(realpath)
 	if err != nil {
-		log.Warnf("fsclient - list %s failed", path)
-		return nil, errors.Trace(err)
+		c.log.Warnf("fsclient - list %s failed", path)
+		return nil, mapError(err)
 	}
 	defer f.Close()
 
 	names, err := f.Readdirnames(-1)
 	if err != nil {
-		log.Warnf("fsclient - list %s failed", path)
-		return nil, errors.Trace(err)
+		c.log.Warnf("fsclient - list %s failed", path)
+		return nil, mapError(err)
 	}
 	sort.Strings(names)
 
@@ -314,22 +349,171 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 	return results, nil
 }
 
+func (c *Client) ReadMany(paths []string, must bool) ([][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, mapError(ErrClosedClient)
+	}
+
+	if err := c.lockFs(); err != nil {
+		return nil, err
+	}
+	defer c.unlockFs()
+
+	data := make([][]byte, len(paths))
+	for i, path := range paths {
+		b, err := ioutil.ReadFile(c.realpath(path))
+		if err != nil {
+			if os.IsNotExist(err) && !must {
+				continue
+			}
+			c.log.Warnf("fsclient - read %s failed", path)
+			return nil, mapError(err)
+		}
+		data[i] = b
+	}
+	return data, nil
+}
+
+func (c *Client) ListWithValues(path string, must bool) (map[string][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, mapError(ErrClosedClient)
+	}
+
+	if err := c.lockFs(); err != nil {
+		return nil, err
+	}
+	defer c.unlockFs()
+
+	infos, err := ioutil.ReadDir(c.realpath(path))
+	if err != nil {
+		if os.IsNotExist(err) && !must {
+			return nil, nil
+		}
+		c.log.Warnf("fsclient - list %s failed", path)
+		return nil, mapError(err)
+	}
+
+	values := make(map[string][]byte, len(infos))
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		name := filepath.Join(path, info.Name())
+		b, err := ioutil.ReadFile(c.realpath(name))
+		if err != nil {
+			c.log.Warnf("fsclient - read %s failed", name)
+			return nil, mapError(err)
+		}
+		values[name] = b
+	}
+	return values, nil
+}
+
 var ErrNotSupported = errors.New("not supported")
 
-func (c *Client) WatchInOrder(path string) (<-chan struct{}, []string, error) {
+// CreateInOrder names the node after the largest numeric child of path plus
+// one. The flock taken around it serializes creators across processes.
+func (c *Client) CreateInOrder(path string, data []byte) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return "", mapError(ErrClosedClient)
+	}
+
+	if err := c.lockFs(); err != nil {
+		return "", err
+	}
+	defer c.unlockFs()
+
+	names, err := c.readdirnames(path)
+	if err != nil {
+		return "", err
+	}
+	var last int
+	for _, name := range names {
+		if n, err := strconv.Atoi(name); err == nil && n > last {
+			last = n
+		}
+	}
+	node := filepath.Join(path, fmt.Sprintf("%06d", last+1))
+	if err := c.writeFile(c.realpath(node), data, true); err != nil {
+		c.log.Warnf("fsclient - create %s failed", node)
+		return "", err
+	}
+	c.log.Infof("fsclient - create %s OK", node)
+	return node, nil
+}
+
+func (c *Client) readdirnames(path string) ([]string, error) {
+	f, err := os.Open(c.realpath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, mapError(err)
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// WatchPollInterval is how often WatchInOrder rescans the watched directory,
+// the filesystem gives no portable change notification.
+var WatchPollInterval = time.Second
+
+func (c *Client) WatchInOrder(path string) (<-chan client.Event, []string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, nil, errors.Trace(ErrClosedClient)
+		return nil, nil, mapError(ErrClosedClient)
+	}
+	names, err := c.readdirnames(path)
+	if err != nil {
+		return nil, nil, err
 	}
-	return nil, nil, errors.Trace(ErrNotSupported)
+	var paths []string
+	for _, name := range names {
+		paths = append(paths, filepath.Join(path, name))
+	}
+	signal := make(chan client.Event, 1)
+	go func() {
+		defer close(signal)
+		for {
+			time.Sleep(WatchPollInterval)
+			c.Lock()
+			closed := c.closed
+			var current []string
+			var err error
+			if !closed {
+				current, err = c.readdirnames(path)
+			}
+			c.Unlock()
+			switch {
+			case closed || err != nil:
+				signal <- client.Event{Type: client.EventNotWatching}
+				return
+			case strings.Join(current, "/") != strings.Join(names, "/"):
+				signal <- client.Event{Type: client.EventNodeChildrenChanged}
+				return
+			}
+		}
+	}()
+	return signal, paths, nil
 }
 
 func (c *Client) CreateEphemeral(path string, data []byte) (<-chan struct{}, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 	return nil, errors.Trace(ErrNotSupported)
 }
@@ -338,7 +522,7 @@ func (c *Client) CreateEphemeralInOrder(path string, data []byte) (<-chan struct
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, "", errors.Trace(ErrClosedClient)
+		return nil, "", mapError(ErrClosedClient)
 	}
 	return nil, "", errors.Trace(ErrNotSupported)
 }
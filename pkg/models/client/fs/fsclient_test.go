@@ -0,0 +1,53 @@
+package fsclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/IceFireDB/kit/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadMany(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsclient")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	c, err := New(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, c.Create("/p/slots/slot-0000", []byte("0")))
+	assert.Nil(t, c.Create("/p/slots/slot-0001", []byte("1")))
+
+	values, err := c.ListWithValues("/p/slots", true)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]byte{
+		"/p/slots/slot-0000": []byte("0"),
+		"/p/slots/slot-0001": []byte("1"),
+	}, values)
+
+	data, err := c.ReadMany([]string{"/p/slots/slot-0001", "/p/slots/slot-0002"}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("1"), nil}, data)
+
+	_, err = c.ReadMany([]string{"/p/slots/slot-0002"}, true)
+	assert.NotNil(t, err)
+
+	values, err = c.ListWithValues("/p/group", false)
+	assert.Nil(t, err)
+	assert.Nil(t, values)
+}
+
+func TestNewWithLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsclient")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	c, err := NewWithLogger(dir, logger.NewLogger("fsclient-test", logger.WithOutput(&buf)))
+	assert.Nil(t, err)
+	assert.Nil(t, c.Create("/p/slots/slot-0000", []byte("0")))
+	assert.Contains(t, buf.String(), "fsclient - create /p/slots/slot-0000 OK")
+	assert.Contains(t, buf.String(), "fsclient-test")
+}
@@ -0,0 +1,232 @@
+package memclient
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/CodisLabs/codis/pkg/utils/errors"
+	"github.com/IceFireDB/kit/pkg/models/client"
+)
+
+var ErrClosedClient = errors.New("use of closed mem client")
+
+var (
+	ErrNotExist   = errors.New("mem: node not exist")
+	ErrNodeExists = errors.New("mem: node already exists")
+)
+
+// Client keeps nodes in process memory. It behaves like the zk client,
+// List returns direct children only, and is meant for tests and tools
+// that don't need a shared coordinator. Delete removes a whole subtree.
+type Client struct {
+	sync.Mutex
+
+	nodes    map[string][]byte
+	sequence map[string]int
+	watchers map[string][]chan client.Event
+
+	closed bool
+}
+
+func New() *Client {
+	return &Client{
+		nodes:    make(map[string][]byte),
+		sequence: make(map[string]int),
+		watchers: make(map[string][]chan client.Event),
+	}
+}
+
+func (c *Client) Close() error {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	for dir, watchers := range c.watchers {
+		for _, w := range watchers {
+			w <- client.Event{Type: client.EventNotWatching}
+			close(w)
+		}
+		delete(c.watchers, dir)
+	}
+	return nil
+}
+
+// notify fires the children watchers of the parent of p.
+func (c *Client) notify(p string) {
+	dir := path.Dir(p)
+	for _, w := range c.watchers[dir] {
+		w <- client.Event{Type: client.EventNodeChildrenChanged}
+		close(w)
+	}
+	delete(c.watchers, dir)
+}
+
+func (c *Client) Create(p string, data []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return client.Wrap(client.ErrClosed, ErrClosedClient)
+	}
+	p = path.Clean(p)
+	if _, ok := c.nodes[p]; ok {
+		return client.Wrap(client.ErrExists, ErrNodeExists)
+	}
+	c.nodes[p] = append([]byte{}, data...)
+	c.notify(p)
+	return nil
+}
+
+func (c *Client) CreateInOrder(dir string, data []byte) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return "", client.Wrap(client.ErrClosed, ErrClosedClient)
+	}
+	dir = path.Clean(dir)
+	c.sequence[dir]++
+	p := path.Join(dir, fmt.Sprintf("%06d", c.sequence[dir]))
+	c.nodes[p] = append([]byte{}, data...)
+	c.notify(p)
+	return p, nil
+}
+
+func (c *Client) Update(p string, data []byte) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return client.Wrap(client.ErrClosed, ErrClosedClient)
+	}
+	p = path.Clean(p)
+	_, exists := c.nodes[p]
+	c.nodes[p] = append([]byte{}, data...)
+	if !exists {
+		c.notify(p)
+	}
+	return nil
+}
+
+func (c *Client) Delete(p string) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return client.Wrap(client.ErrClosed, ErrClosedClient)
+	}
+	p = path.Clean(p)
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	for k := range c.nodes {
+		if k == p || strings.HasPrefix(k, prefix) {
+			delete(c.nodes, k)
+			c.notify(k)
+		}
+	}
+	return nil
+}
+
+func (c *Client) Read(p string, must bool) ([]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, client.Wrap(client.ErrClosed, ErrClosedClient)
+	}
+	return c.read(path.Clean(p), must)
+}
+
+func (c *Client) read(p string, must bool) ([]byte, error) {
+	b, ok := c.nodes[p]
+	if !ok {
+		if must {
+			return nil, client.Wrap(client.ErrNotFound, ErrNotExist)
+		}
+		return nil, nil
+	}
+	return append([]byte{}, b...), nil
+}
+
+func (c *Client) List(p string, must bool) ([]string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, client.Wrap(client.ErrClosed, ErrClosedClient)
+	}
+	return c.list(path.Clean(p), must)
+}
+
+func (c *Client) list(p string, must bool) ([]string, error) {
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	children := make(map[string]bool)
+	for k := range c.nodes {
+		if strings.HasPrefix(k, prefix) {
+			name := strings.SplitN(k[len(prefix):], "/", 2)[0]
+			children[prefix+name] = true
+		}
+	}
+	if len(children) == 0 {
+		if _, ok := c.nodes[p]; !ok && must {
+			return nil, client.Wrap(client.ErrNotFound, ErrNotExist)
+		}
+		return nil, nil
+	}
+	paths := make([]string, 0, len(children))
+	for k := range children {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (c *Client) ReadMany(paths []string, must bool) ([][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, client.Wrap(client.ErrClosed, ErrClosedClient)
+	}
+	data := make([][]byte, len(paths))
+	for i, p := range paths {
+		b, err := c.read(path.Clean(p), must)
+		if err != nil {
+			return nil, err
+		}
+		data[i] = b
+	}
+	return data, nil
+}
+
+func (c *Client) ListWithValues(p string, must bool) (map[string][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, client.Wrap(client.ErrClosed, ErrClosedClient)
+	}
+	paths, err := c.list(path.Clean(p), must)
+	if err != nil || paths == nil {
+		return nil, err
+	}
+	values := make(map[string][]byte, len(paths))
+	for _, child := range paths {
+		if b, ok := c.nodes[child]; ok {
+			values[child] = append([]byte{}, b...)
+		}
+	}
+	return values, nil
+}
+
+func (c *Client) WatchInOrder(p string) (<-chan client.Event, []string, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, nil, client.Wrap(client.ErrClosed, ErrClosedClient)
+	}
+	p = path.Clean(p)
+	paths, err := c.list(p, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	signal := make(chan client.Event, 1)
+	c.watchers[p] = append(c.watchers[p], signal)
+	return signal, paths, nil
+}
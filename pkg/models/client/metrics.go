@@ -0,0 +1,186 @@
+package client
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultPrefixDepth keeps /icefire/<product> of every path as the prefix
+// label, which bounds the label values by the number of products.
+const DefaultPrefixDepth = 2
+
+// Metrics holds the collectors updated by the clients WithMetrics returns.
+// One Metrics can be shared by several clients, they are told apart by the
+// backend label.
+type Metrics struct {
+	// PrefixDepth is the number of leading path segments used as the
+	// prefix label.
+	PrefixDepth int
+
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// NewMetrics creates the coordinator collectors and registers them on reg.
+func NewMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	labels := []string{"backend", "op", "prefix"}
+	m := &Metrics{
+		PrefixDepth: DefaultPrefixDepth,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "icefire",
+			Subsystem: "coordinator",
+			Name:      "requests_total",
+			Help:      "Coordinator requests by operation.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "icefire",
+			Subsystem: "coordinator",
+			Name:      "errors_total",
+			Help:      "Failed coordinator requests by operation and error kind.",
+		}, append(labels, "kind")),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "icefire",
+			Subsystem: "coordinator",
+			Name:      "request_duration_seconds",
+			Help:      "Coordinator request latency by operation.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 16),
+		}, labels),
+	}
+	for _, c := range []prometheus.Collector{m.requests, m.errors, m.latency} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) prefix(p string) string {
+	split := strings.SplitN(strings.TrimPrefix(p, "/"), "/", m.PrefixDepth+1)
+	if len(split) > m.PrefixDepth {
+		split = split[:m.PrefixDepth]
+	}
+	return "/" + strings.Join(split, "/")
+}
+
+var errorKinds = []struct {
+	kind error
+	name string
+}{
+	{ErrNotFound, "not_found"},
+	{ErrExists, "exists"},
+	{ErrVersionConflict, "version_conflict"},
+	{ErrClosed, "closed"},
+	{ErrTimeout, "timeout"},
+}
+
+func errorKind(err error) string {
+	for _, k := range errorKinds {
+		if Is(err, k.kind) {
+			return k.name
+		}
+	}
+	return "other"
+}
+
+func (m *Metrics) observe(backend, op, path string, start time.Time, err error) {
+	prefix := m.prefix(path)
+	m.requests.WithLabelValues(backend, op, prefix).Inc()
+	m.latency.WithLabelValues(backend, op, prefix).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.errors.WithLabelValues(backend, op, prefix, errorKind(err)).Inc()
+	}
+}
+
+type metrics struct {
+	client  Client
+	metrics *Metrics
+	backend string
+}
+
+// WithMetrics returns a Client recording every call of c on m.
+func WithMetrics(c Client, m *Metrics, backend string) Client {
+	return &metrics{client: c, metrics: m, backend: backend}
+}
+
+func (m *metrics) Create(path string, data []byte) (err error) {
+	defer func(start time.Time) {
+		m.metrics.observe(m.backend, "create", path, start, err)
+	}(time.Now())
+	return m.client.Create(path, data)
+}
+
+func (m *metrics) CreateInOrder(path string, data []byte) (node string, err error) {
+	defer func(start time.Time) {
+		m.metrics.observe(m.backend, "create_in_order", path, start, err)
+	}(time.Now())
+	return m.client.CreateInOrder(path, data)
+}
+
+func (m *metrics) Update(path string, data []byte) (err error) {
+	defer func(start time.Time) {
+		m.metrics.observe(m.backend, "update", path, start, err)
+	}(time.Now())
+	return m.client.Update(path, data)
+}
+
+func (m *metrics) Delete(path string) (err error) {
+	defer func(start time.Time) {
+		m.metrics.observe(m.backend, "delete", path, start, err)
+	}(time.Now())
+	return m.client.Delete(path)
+}
+
+func (m *metrics) Read(path string, must bool) (data []byte, err error) {
+	defer func(start time.Time) {
+		m.metrics.observe(m.backend, "read", path, start, err)
+	}(time.Now())
+	return m.client.Read(path, must)
+}
+
+func (m *metrics) List(path string, must bool) (paths []string, err error) {
+	defer func(start time.Time) {
+		m.metrics.observe(m.backend, "list", path, start, err)
+	}(time.Now())
+	return m.client.List(path, must)
+}
+
+// ReadMany is labelled with the prefix of its first path.
+func (m *metrics) ReadMany(paths []string, must bool) (data [][]byte, err error) {
+	var path string
+	if len(paths) != 0 {
+		path = paths[0]
+	}
+	defer func(start time.Time) {
+		m.metrics.observe(m.backend, "read_many", path, start, err)
+	}(time.Now())
+	return ReadMany(m.client, paths, must)
+}
+
+func (m *metrics) ListWithValues(path string, must bool) (values map[string][]byte, err error) {
+	defer func(start time.Time) {
+		m.metrics.observe(m.backend, "list_with_values", path, start, err)
+	}(time.Now())
+	return ListWithValues(m.client, path, must)
+}
+
+func (m *metrics) Close() error {
+	return m.client.Close()
+}
+
+func (m *metrics) SessionEvents() <-chan Event {
+	return SessionEvents(m.client)
+}
+
+func (m *metrics) WithParentSpan(parent Span) Client {
+	return &metrics{client: WithParentSpan(m.client, parent), metrics: m.metrics, backend: m.backend}
+}
+
+func (m *metrics) WatchInOrder(path string) (signal <-chan Event, paths []string, err error) {
+	defer func(start time.Time) {
+		m.metrics.observe(m.backend, "watch_in_order", path, start, err)
+	}(time.Now())
+	return m.client.WatchInOrder(path)
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"path"
+	"strings"
+)
+
+type namespace struct {
+	client Client
+	root   string
+}
+
+// WithNamespace returns a Client that prefixes every path with root and
+// strips it from the paths it returns, so several environments can share
+// one coordinator without seeing each other's nodes.
+func WithNamespace(c Client, root string) Client {
+	root = path.Clean("/" + root)
+	if root == "/" {
+		return c
+	}
+	return &namespace{client: c, root: root}
+}
+
+func (n *namespace) full(p string) string {
+	return path.Join(n.root, p)
+}
+
+func (n *namespace) strip(p string) string {
+	if p == n.root {
+		return "/"
+	}
+	return strings.TrimPrefix(p, n.root)
+}
+
+func (n *namespace) stripAll(paths []string) []string {
+	for i, p := range paths {
+		paths[i] = n.strip(p)
+	}
+	return paths
+}
+
+func (n *namespace) Create(p string, data []byte) error {
+	return n.client.Create(n.full(p), data)
+}
+
+func (n *namespace) CreateInOrder(p string, data []byte) (string, error) {
+	node, err := n.client.CreateInOrder(n.full(p), data)
+	return n.strip(node), err
+}
+
+func (n *namespace) Update(p string, data []byte) error {
+	return n.client.Update(n.full(p), data)
+}
+
+func (n *namespace) Delete(p string) error {
+	return n.client.Delete(n.full(p))
+}
+
+func (n *namespace) Read(p string, must bool) ([]byte, error) {
+	return n.client.Read(n.full(p), must)
+}
+
+func (n *namespace) List(p string, must bool) ([]string, error) {
+	paths, err := n.client.List(n.full(p), must)
+	return n.stripAll(paths), err
+}
+
+func (n *namespace) ReadMany(paths []string, must bool) ([][]byte, error) {
+	full := make([]string, len(paths))
+	for i, p := range paths {
+		full[i] = n.full(p)
+	}
+	return ReadMany(n.client, full, must)
+}
+
+func (n *namespace) ListWithValues(p string, must bool) (map[string][]byte, error) {
+	values, err := ListWithValues(n.client, n.full(p), must)
+	if values == nil {
+		return nil, err
+	}
+	stripped := make(map[string][]byte, len(values))
+	for k, v := range values {
+		stripped[n.strip(k)] = v
+	}
+	return stripped, err
+}
+
+func (n *namespace) Close() error {
+	return n.client.Close()
+}
+
+func (n *namespace) SessionEvents() <-chan Event {
+	return SessionEvents(n.client)
+}
+
+func (n *namespace) WithParentSpan(parent Span) Client {
+	return &namespace{client: WithParentSpan(n.client, parent), root: n.root}
+}
+
+func (n *namespace) WatchInOrder(p string) (<-chan Event, []string, error) {
+	signal, paths, err := n.client.WatchInOrder(n.full(p))
+	return signal, n.stripAll(paths), err
+}
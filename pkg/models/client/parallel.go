@@ -0,0 +1,32 @@
+package client
+
+import "sync"
+
+// MaxParallel bounds the number of in-flight requests issued by ParallelDo.
+const MaxParallel = 32
+
+// ParallelDo calls fn for every index in [0, n) from at most MaxParallel
+// goroutines and returns the first error reported.
+func ParallelDo(n int, fn func(i int) error) error {
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	sem := make(chan struct{}, MaxParallel)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(i); err != nil {
+				once.Do(func() { first = err })
+			}
+		}(i)
+	}
+	wg.Wait()
+	return first
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures WithRetry. Backends export IsRetryable and
+// IsNotSent helpers suitable for the two classifiers.
+type RetryPolicy struct {
+	// MaxAttempts counts the first call, values below 1 mean 1.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry, doubled after each
+	// attempt up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Jitter randomizes every delay by up to this fraction of it, in [0, 1].
+	Jitter float64
+
+	// Retryable reports transient errors. Idempotent operations (Read, List,
+	// ReadMany, ListWithValues, Update, Delete, WatchInOrder) are retried on them.
+	Retryable func(err error) bool
+	// NotSent reports errors raised before the request could be applied.
+	// Create and CreateInOrder are retried on those only, a nil NotSent
+	// never retries them.
+	NotSent func(err error) bool
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	MinBackoff:  time.Millisecond * 100,
+	MaxBackoff:  time.Second * 2,
+	Jitter:      0.2,
+}
+
+type retry struct {
+	client Client
+	policy RetryPolicy
+}
+
+// WithRetry returns a Client retrying the calls of c according to policy.
+func WithRetry(c Client, policy RetryPolicy) Client {
+	return &retry{client: c, policy: policy}
+}
+
+func (r *retry) shouldRetry(err error, idempotent bool) bool {
+	if idempotent && r.policy.Retryable != nil && r.policy.Retryable(err) {
+		return true
+	}
+	return r.policy.NotSent != nil && r.policy.NotSent(err)
+}
+
+func (r *retry) delay(backoff time.Duration) time.Duration {
+	if r.policy.Jitter <= 0 {
+		return backoff
+	}
+	spread := float64(backoff) * r.policy.Jitter
+	return backoff + time.Duration(spread*(2*rand.Float64()-1))
+}
+
+func (r *retry) do(idempotent bool, fn func() error) error {
+	backoff := r.policy.MinBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.policy.MaxAttempts || !r.shouldRetry(err, idempotent) {
+			return err
+		}
+		time.Sleep(r.delay(backoff))
+		if backoff *= 2; backoff > r.policy.MaxBackoff {
+			backoff = r.policy.MaxBackoff
+		}
+	}
+}
+
+func (r *retry) Create(path string, data []byte) error {
+	return r.do(false, func() error {
+		return r.client.Create(path, data)
+	})
+}
+
+func (r *retry) CreateInOrder(path string, data []byte) (node string, err error) {
+	err = r.do(false, func() error {
+		node, err = r.client.CreateInOrder(path, data)
+		return err
+	})
+	return node, err
+}
+
+func (r *retry) Update(path string, data []byte) error {
+	return r.do(true, func() error {
+		return r.client.Update(path, data)
+	})
+}
+
+func (r *retry) Delete(path string) error {
+	return r.do(true, func() error {
+		return r.client.Delete(path)
+	})
+}
+
+func (r *retry) Read(path string, must bool) (data []byte, err error) {
+	err = r.do(true, func() error {
+		data, err = r.client.Read(path, must)
+		return err
+	})
+	return data, err
+}
+
+func (r *retry) List(path string, must bool) (paths []string, err error) {
+	err = r.do(true, func() error {
+		paths, err = r.client.List(path, must)
+		return err
+	})
+	return paths, err
+}
+
+func (r *retry) ReadMany(paths []string, must bool) (data [][]byte, err error) {
+	err = r.do(true, func() error {
+		data, err = ReadMany(r.client, paths, must)
+		return err
+	})
+	return data, err
+}
+
+func (r *retry) ListWithValues(path string, must bool) (values map[string][]byte, err error) {
+	err = r.do(true, func() error {
+		values, err = ListWithValues(r.client, path, must)
+		return err
+	})
+	return values, err
+}
+
+func (r *retry) Close() error {
+	return r.client.Close()
+}
+
+func (r *retry) SessionEvents() <-chan Event {
+	return SessionEvents(r.client)
+}
+
+func (r *retry) WithParentSpan(parent Span) Client {
+	return &retry{client: WithParentSpan(r.client, parent), policy: r.policy}
+}
+
+func (r *retry) WatchInOrder(path string) (signal <-chan Event, paths []string, err error) {
+	err = r.do(true, func() error {
+		signal, paths, err = r.client.WatchInOrder(path)
+		return err
+	})
+	return signal, paths, err
+}
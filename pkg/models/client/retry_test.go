@@ -0,0 +1,64 @@
+package client_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IceFireDB/kit/pkg/models/client"
+	memclient "github.com/IceFireDB/kit/pkg/models/client/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+type flaky struct {
+	*memclient.Client
+	failures int
+	calls    int
+}
+
+func (f *flaky) Read(path string, must bool) ([]byte, error) {
+	if f.calls++; f.calls <= f.failures {
+		return nil, errTransient
+	}
+	return f.Client.Read(path, must)
+}
+
+func (f *flaky) Create(path string, data []byte) error {
+	if f.calls++; f.calls <= f.failures {
+		return errTransient
+	}
+	return f.Client.Create(path, data)
+}
+
+func TestRetry(t *testing.T) {
+	policy := client.RetryPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond * 4,
+		Retryable:   func(err error) bool { return err == errTransient },
+	}
+
+	f := &flaky{Client: memclient.New(), failures: 2}
+	assert.Nil(t, f.Client.Create("/a", []byte("x")))
+	b, err := client.WithRetry(f, policy).Read("/a", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("x"), b)
+	assert.Equal(t, 3, f.calls)
+
+	f = &flaky{Client: memclient.New(), failures: 3}
+	_, err = client.WithRetry(f, policy).Read("/a", true)
+	assert.Equal(t, errTransient, err)
+	assert.Equal(t, 3, f.calls)
+
+	// Create is not idempotent, it is retried only on NotSent errors.
+	f = &flaky{Client: memclient.New(), failures: 1}
+	assert.Equal(t, errTransient, client.WithRetry(f, policy).Create("/b", nil))
+	assert.Equal(t, 1, f.calls)
+
+	policy.NotSent = policy.Retryable
+	f = &flaky{Client: memclient.New(), failures: 1}
+	assert.Nil(t, client.WithRetry(f, policy).Create("/b", nil))
+	assert.Equal(t, 2, f.calls)
+}
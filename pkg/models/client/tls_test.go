@@ -0,0 +1,103 @@
+package client_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/IceFireDB/kit/pkg/models/client"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeCert writes a self-signed certificate for localhost and its key to
+// dir, returning the parsed certificate and both paths.
+func writeCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return cert, certFile, keyFile
+}
+
+func TestTLSClientConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kit-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	cert, certFile, keyFile := writeCert(t, dir)
+
+	config, err := (&client.TLSConfig{}).ClientConfig()
+	assert.Nil(t, err)
+	assert.Nil(t, config.RootCAs)
+	assert.Empty(t, config.Certificates)
+	assert.False(t, config.InsecureSkipVerify)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+
+	config, err = (&client.TLSConfig{
+		CAFile:     certFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: "localhost",
+	}).ClientConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost", config.ServerName)
+	_, err = cert.Verify(x509.VerifyOptions{DNSName: "localhost", Roots: config.RootCAs})
+	assert.Nil(t, err)
+	assert.Len(t, config.Certificates, 1)
+
+	config, err = (&client.TLSConfig{InsecureSkipVerify: true}).ClientConfig()
+	assert.Nil(t, err)
+	assert.True(t, config.InsecureSkipVerify)
+}
+
+func TestTLSClientConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kit-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	_, certFile, keyFile := writeCert(t, dir)
+	missing := filepath.Join(dir, "missing.pem")
+
+	for _, tc := range []struct {
+		name   string
+		config client.TLSConfig
+	}{
+		{"missing ca file", client.TLSConfig{CAFile: missing}},
+		{"ca file without certificate", client.TLSConfig{CAFile: keyFile}},
+		{"missing cert file", client.TLSConfig{CertFile: missing, KeyFile: keyFile}},
+		{"missing key file", client.TLSConfig{CertFile: certFile, KeyFile: missing}},
+		{"cert without key", client.TLSConfig{CertFile: certFile}},
+	} {
+		config, err := tc.config.ClientConfig()
+		assert.NotNil(t, err, tc.name)
+		assert.Nil(t, config, tc.name)
+	}
+}
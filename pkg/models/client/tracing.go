@@ -0,0 +1,254 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+// Attribute is a key/value pair attached to a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer starts spans, it is the hook to plug a tracing library in.
+type Tracer interface {
+	// Start starts a span, a child of parent unless parent is nil. The
+	// parent may come from another Tracer, which the Tracer may ignore.
+	Start(parent Span, name string, attrs ...Attribute) Span
+}
+
+// SpanParenter is implemented by the Clients of WithTracing, and by the
+// wrappers of this package by forwarding to the client they wrap.
+type SpanParenter interface {
+	// WithParentSpan returns a Client starting its spans as children of
+	// parent.
+	WithParentSpan(parent Span) Client
+}
+
+// WithParentSpan returns a Client whose spans are children of parent, or c
+// itself when it traces nothing.
+func WithParentSpan(c Client, parent Span) Client {
+	if p, ok := c.(SpanParenter); ok {
+		return p.WithParentSpan(parent)
+	}
+	return c
+}
+
+// Span is one timed operation. End must be called exactly once, with the
+// error the operation failed with, if any.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	End(err error)
+}
+
+// NoopTracer drops every span.
+var NoopTracer Tracer = noopTracer{}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(parent Span, name string, attrs ...Attribute) Span {
+	return noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(attrs ...Attribute) {}
+
+func (noopSpan) End(err error) {}
+
+// RecordedSpan is a span ended on a MemoryTracer. IDs count from 1, the
+// ParentID of a root span is 0.
+type RecordedSpan struct {
+	ID, ParentID int
+	Name         string
+	Attributes   map[string]interface{}
+	Start, End   time.Time
+	Err          error
+}
+
+func (s *RecordedSpan) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// MemoryTracer keeps ended spans in memory, it is meant for tests.
+type MemoryTracer struct {
+	sync.Mutex
+	spans  []RecordedSpan
+	lastID int
+}
+
+func NewMemoryTracer() *MemoryTracer {
+	return &MemoryTracer{}
+}
+
+func (t *MemoryTracer) Start(parent Span, name string, attrs ...Attribute) Span {
+	t.Lock()
+	t.lastID++
+	id := t.lastID
+	t.Unlock()
+	var parentID int
+	if p, ok := parent.(*memorySpan); ok && p.tracer == t {
+		parentID = p.span.ID
+	}
+	s := &memorySpan{tracer: t, span: RecordedSpan{
+		ID:         id,
+		ParentID:   parentID,
+		Name:       name,
+		Attributes: make(map[string]interface{}, len(attrs)),
+		Start:      time.Now(),
+	}}
+	s.SetAttributes(attrs...)
+	return s
+}
+
+// Spans returns the ended spans in the order they ended.
+func (t *MemoryTracer) Spans() []RecordedSpan {
+	t.Lock()
+	defer t.Unlock()
+	return append([]RecordedSpan{}, t.spans...)
+}
+
+func (t *MemoryTracer) Reset() {
+	t.Lock()
+	defer t.Unlock()
+	t.spans, t.lastID = nil, 0
+}
+
+type memorySpan struct {
+	tracer *MemoryTracer
+	span   RecordedSpan
+}
+
+func (s *memorySpan) SetAttributes(attrs ...Attribute) {
+	for _, a := range attrs {
+		s.span.Attributes[a.Key] = a.Value
+	}
+}
+
+func (s *memorySpan) End(err error) {
+	s.span.End, s.span.Err = time.Now(), err
+	s.tracer.Lock()
+	defer s.tracer.Unlock()
+	s.tracer.spans = append(s.tracer.spans, s.span)
+}
+
+type tracing struct {
+	client  Client
+	tracer  Tracer
+	backend string
+	parent  Span
+}
+
+// WithTracing returns a Client starting a span on t for every call of c,
+// named client.<Op> and carrying the backend, path and size in bytes or
+// nodes of the data written or returned. See WithParentSpan to start them as
+// children of the span of the caller.
+func WithTracing(c Client, t Tracer, backend string) Client {
+	return &tracing{client: c, tracer: t, backend: backend}
+}
+
+func (t *tracing) start(op, path string) Span {
+	return t.tracer.Start(t.parent, "client."+op, Attr("backend", t.backend), Attr("path", path))
+}
+
+func (t *tracing) WithParentSpan(parent Span) Client {
+	return &tracing{client: t.client, tracer: t.tracer, backend: t.backend, parent: parent}
+}
+
+func (t *tracing) Create(path string, data []byte) error {
+	span := t.start("Create", path)
+	span.SetAttributes(Attr("size", len(data)))
+	err := t.client.Create(path, data)
+	span.End(err)
+	return err
+}
+
+func (t *tracing) CreateInOrder(path string, data []byte) (string, error) {
+	span := t.start("CreateInOrder", path)
+	span.SetAttributes(Attr("size", len(data)))
+	node, err := t.client.CreateInOrder(path, data)
+	span.SetAttributes(Attr("node", node))
+	span.End(err)
+	return node, err
+}
+
+func (t *tracing) Update(path string, data []byte) error {
+	span := t.start("Update", path)
+	span.SetAttributes(Attr("size", len(data)))
+	err := t.client.Update(path, data)
+	span.End(err)
+	return err
+}
+
+func (t *tracing) Delete(path string) error {
+	span := t.start("Delete", path)
+	err := t.client.Delete(path)
+	span.End(err)
+	return err
+}
+
+func (t *tracing) Read(path string, must bool) ([]byte, error) {
+	span := t.start("Read", path)
+	data, err := t.client.Read(path, must)
+	span.SetAttributes(Attr("size", len(data)))
+	span.End(err)
+	return data, err
+}
+
+func (t *tracing) List(path string, must bool) ([]string, error) {
+	span := t.start("List", path)
+	paths, err := t.client.List(path, must)
+	span.SetAttributes(Attr("nodes", len(paths)))
+	span.End(err)
+	return paths, err
+}
+
+// ReadMany spans carry the first path only.
+func (t *tracing) ReadMany(paths []string, must bool) ([][]byte, error) {
+	var path string
+	if len(paths) != 0 {
+		path = paths[0]
+	}
+	span := t.start("ReadMany", path)
+	data, err := ReadMany(t.client, paths, must)
+	var size int
+	for _, b := range data {
+		size += len(b)
+	}
+	span.SetAttributes(Attr("nodes", len(paths)), Attr("size", size))
+	span.End(err)
+	return data, err
+}
+
+func (t *tracing) ListWithValues(path string, must bool) (map[string][]byte, error) {
+	span := t.start("ListWithValues", path)
+	values, err := ListWithValues(t.client, path, must)
+	var size int
+	for _, b := range values {
+		size += len(b)
+	}
+	span.SetAttributes(Attr("nodes", len(values)), Attr("size", size))
+	span.End(err)
+	return values, err
+}
+
+func (t *tracing) Close() error {
+	return t.client.Close()
+}
+
+func (t *tracing) SessionEvents() <-chan Event {
+	return SessionEvents(t.client)
+}
+
+func (t *tracing) WatchInOrder(path string) (<-chan Event, []string, error) {
+	span := t.start("WatchInOrder", path)
+	signal, paths, err := t.client.WatchInOrder(path)
+	span.SetAttributes(Attr("nodes", len(paths)))
+	span.End(err)
+	return signal, paths, err
+}
@@ -4,7 +4,10 @@
 package zkclient
 
 import (
+	"crypto/tls"
+	stderrors "errors"
 	"fmt"
+	"net"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -14,15 +17,17 @@ import (
 	"github.com/IceFireDB/kit/pkg/models/client"
 
 	"github.com/CodisLabs/codis/pkg/utils/errors"
-	"github.com/CodisLabs/codis/pkg/utils/log"
+	"github.com/IceFireDB/kit/pkg/logger"
 	"github.com/samuel/go-zookeeper/zk"
 )
 
 var ErrClosedClient = errors.New("use of closed zk client")
 
-var DefaultLogfunc = func(format string, v ...interface{}) {
-	log.Info("zookeeper - " + fmt.Sprintf(format, v...))
-}
+var log logger.Logger = logger.NewLogger("zkclient")
+
+// DefaultLogfunc writes the log of the zookeeper library to the "zookeeper"
+// scope of pkg/logger.
+var DefaultLogfunc = logger.NewPrintfLogger(logger.NewLogger("zookeeper"), logger.InfoLevel).Printf
 
 type Client struct {
 	sync.Mutex
@@ -33,9 +38,43 @@ type Client struct {
 	password string
 	timeout  time.Duration
 
+	log    logger.Logger
 	logger *zkLogger
 	dialAt time.Time
 	closed bool
+
+	tls *tls.Config
+
+	// backoff is the minimum delay between two resets of the connection,
+	// doubled after every reset and restored once a request succeeds.
+	backoff time.Duration
+	// session is closed and replaced every time a new session is established.
+	session chan struct{}
+	done    chan struct{}
+
+	ephemerals    map[string]*ephemeral
+	sessionEvents chan client.Event
+}
+
+const (
+	minResetBackoff = time.Second
+	maxResetBackoff = time.Second * 30
+)
+
+// ephemeral remembers an ephemeral node created by this client so it can be
+// created again when the session it belonged to expires.
+type ephemeral struct {
+	data []byte
+	// prefix is the path given to CreateEphemeralInOrder, empty for CreateEphemeral.
+	prefix string
+}
+
+// nodeCreator is the part of *zk.Conn that creates nodes, and deletes the
+// ones recover re-created for nobody.
+type nodeCreator interface {
+	Exists(path string) (bool, *zk.Stat, error)
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Delete(path string, version int32) error
 }
 
 type zkLogger struct {
@@ -53,12 +92,53 @@ func New(addrlist string, auth string, timeout time.Duration) (*Client, error) {
 }
 
 func NewWithLogfunc(addrlist string, auth string, timeout time.Duration, logfunc func(foramt string, v ...interface{})) (*Client, error) {
+	return newClient(addrlist, auth, timeout, logfunc, nil, nil)
+}
+
+// NewWithTLS talks to servers started with a secure client port. go-zookeeper
+// has no SASL support, so servers should authenticate the client certificate
+// (x509 auth provider) or keep using digest auth on top of TLS.
+func NewWithTLS(addrlist string, auth string, timeout time.Duration, tlsConfig *client.TLSConfig) (*Client, error) {
+	return NewWithLogger(addrlist, auth, timeout, tlsConfig, nil)
+}
+
+// NewWithLogger is NewWithTLS writing the log of the client, and the one of
+// the zookeeper library at Info, to l. A nil tlsConfig disables TLS and a
+// nil l keeps the "zkclient" and "zookeeper" scopes.
+func NewWithLogger(addrlist string, auth string, timeout time.Duration, tlsConfig *client.TLSConfig, l logger.Logger) (*Client, error) {
+	var tlsc *tls.Config
+	if tlsConfig != nil {
+		var err error
+		if tlsc, err = tlsConfig.ClientConfig(); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+	logfunc := DefaultLogfunc
+	if l != nil {
+		logfunc = logger.NewPrintfLogger(l, logger.InfoLevel).Printf
+	}
+	return newClient(addrlist, auth, timeout, logfunc, tlsc, l)
+}
+
+func newClient(addrlist string, auth string, timeout time.Duration, logfunc func(foramt string, v ...interface{}), tlsc *tls.Config, l logger.Logger) (*Client, error) {
 	if timeout <= 0 {
 		timeout = time.Second * 5
 	}
+	if l == nil {
+		l = log
+	}
 	c := &Client{
 		addrlist: addrlist, timeout: timeout,
+		log:    l,
 		logger: &zkLogger{logfunc},
+		tls:    tlsc,
+
+		backoff: minResetBackoff,
+		session: make(chan struct{}),
+		done:    make(chan struct{}),
+
+		ephemerals:    make(map[string]*ephemeral),
+		sessionEvents: make(chan client.Event, 16),
 	}
 	if auth != "" {
 		split := strings.SplitN(auth, ":", 2)
@@ -76,11 +156,16 @@ func NewWithLogfunc(addrlist string, auth string, timeout time.Duration, logfunc
 
 func (c *Client) reset() error {
 	c.dialAt = time.Now()
-	conn, events, err := zk.Connect(strings.Split(c.addrlist, ","), c.timeout)
+	var dialer zk.Dialer = net.DialTimeout
+	if c.tls != nil {
+		dialer = c.dialTLS
+	}
+	conn, events, err := zk.Connect(strings.Split(c.addrlist, ","), c.timeout, zk.WithDialer(dialer))
 	if err != nil {
 		return errors.Trace(err)
 	}
-	if c.conn != nil {
+	replaced := c.conn != nil
+	if replaced {
 		c.conn.Close()
 	}
 	c.conn = conn
@@ -95,14 +180,130 @@ func (c *Client) reset() error {
 		}
 	}
 
-	go func() {
-		for e := range events {
-			log.Debugf("zookeeper event: %+v", e)
-		}
-	}()
+	go c.loop(conn, events)
+
+	if replaced {
+		// closing the old connection dropped its session, reset runs with
+		// the lock held and recover takes it
+		go c.recover(conn)
+	}
 	return nil
 }
 
+// loop follows the session state of conn. go-zookeeper reconnects on its
+// own after an expiry, but the new session has lost our ephemeral nodes
+// and watches, which recover brings back.
+func (c *Client) loop(conn *zk.Conn, events <-chan zk.Event) {
+	var expired bool
+	for e := range events {
+		c.log.Debugf("zookeeper event: %+v", e)
+		if e.Type != zk.EventSession {
+			continue
+		}
+		switch e.State {
+		case zk.StateExpired:
+			c.log.Warnf("zkclient session expired")
+			expired = true
+		case zk.StateHasSession:
+			if expired {
+				expired = false
+				c.Lock()
+				current := !c.closed && c.conn == conn
+				c.Unlock()
+				if current {
+					c.recover(conn)
+				}
+			}
+		}
+	}
+}
+
+// recover re-creates the ephemeral nodes owned by this client on the new
+// session of conn, then wakes up the watches waiting to be re-armed and
+// reports EventSession on SessionEvents. The nodes are created without
+// holding the lock, from a copy of the owned ones: sequential nodes get a
+// new name once created, and a node deleted meanwhile is deleted again.
+func (c *Client) recover(conn nodeCreator) {
+	type owned struct {
+		path string
+		*ephemeral
+	}
+	c.Lock()
+	nodes := make([]owned, 0, len(c.ephemerals))
+	for p, e := range c.ephemerals {
+		nodes = append(nodes, owned{p, e})
+	}
+	c.Unlock()
+
+	created := make(map[*ephemeral]string, len(nodes))
+	for _, n := range nodes {
+		if n.prefix == "" {
+			_, err := c.create(conn, n.path, n.data, zk.FlagEphemeral)
+			if err != nil && errors.NotEqual(err, zk.ErrNodeExists) {
+				c.log.WithError(err).Warnf("zkclient re-create ephemeral node %s failed", n.path)
+				continue
+			}
+			created[n.ephemeral] = n.path
+			continue
+		}
+		node, err := c.create(conn, n.prefix, n.data, zk.FlagEphemeral|zk.FlagSequence)
+		if err != nil {
+			// the entry is kept, try again on the next session
+			c.log.WithError(err).Warnf("zkclient re-create ephemeral node %s failed", n.path)
+			continue
+		}
+		created[n.ephemeral] = node
+		c.log.Infof("zkclient re-create ephemeral node %s as %s", n.path, node)
+	}
+
+	c.Lock()
+	var stale []string
+	for _, n := range nodes {
+		node, ok := created[n.ephemeral]
+		switch {
+		case !ok:
+		case c.ephemerals[n.path] != n.ephemeral:
+			stale = append(stale, node)
+		case node != n.path:
+			delete(c.ephemerals, n.path)
+			c.ephemerals[node] = n.ephemeral
+		}
+	}
+	close(c.session)
+	c.session = make(chan struct{})
+	c.Unlock()
+
+	for _, node := range stale {
+		if err := conn.Delete(node, -1); err != nil && errors.NotEqual(err, zk.ErrNoNode) {
+			c.log.WithError(err).Warnf("zkclient delete ephemeral node %s failed", node)
+		}
+	}
+	select {
+	case c.sessionEvents <- client.Event{Type: client.EventSession}:
+	default:
+	}
+}
+
+// SessionEvents reports EventSession every time the client moved to a new
+// session, after its ephemeral nodes were re-created. Events are dropped
+// while nobody reads them. Clients wrapped by the client package forward it,
+// see client.SessionEvents.
+func (c *Client) SessionEvents() <-chan client.Event {
+	return c.sessionEvents
+}
+
+func (c *Client) dialTLS(network, address string, timeout time.Duration) (net.Conn, error) {
+	config := c.tls.Clone()
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		config.ServerName = host
+	}
+	return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, network, address, config)
+}
+
 func (c *Client) Close() error {
 	c.Lock()
 	defer c.Unlock()
@@ -110,6 +311,7 @@ func (c *Client) Close() error {
 		return nil
 	}
 	c.closed = true
+	close(c.done)
 
 	if c.conn != nil {
 		c.conn.Close()
@@ -121,47 +323,85 @@ func (c *Client) Do(fn func(conn *zk.Conn) error) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
 	return c.shell(fn)
 }
 
 func (c *Client) shell(fn func(conn *zk.Conn) error) error {
 	if err := fn(c.conn); err != nil {
-		for _, e := range []error{zk.ErrNoNode, zk.ErrNodeExists, zk.ErrNotEmpty} {
-			if errors.Equal(e, err) {
-				return err
-			}
-		}
-		if time.Since(c.dialAt) > time.Second {
+		// only a lost connection or session is worth a new one, a reset
+		// drops the ephemeral nodes of the session
+		if IsRetryable(err) && time.Since(c.dialAt) > c.backoff {
 			if err := c.reset(); err != nil {
-				log.DebugErrorf(err, "zkclient reset connection failed")
+				c.log.WithError(err).Debugf("zkclient reset connection failed")
+			}
+			if c.backoff *= 2; c.backoff > maxResetBackoff {
+				c.backoff = maxResetBackoff
 			}
 		}
-		return err
+		return mapError(err)
 	}
+	c.backoff = minResetBackoff
 	return nil
 }
 
+// mapError gives err the client error kind of its zk cause, if it has one.
+func mapError(err error) error {
+	cause := errors.Cause(err)
+	if _, ok := cause.(*client.Error); ok || cause == nil {
+		return cause
+	}
+	switch {
+	case stderrors.Is(cause, zk.ErrNoNode):
+		return client.Wrap(client.ErrNotFound, err)
+	case stderrors.Is(cause, zk.ErrNodeExists):
+		return client.Wrap(client.ErrExists, err)
+	case stderrors.Is(cause, zk.ErrBadVersion):
+		return client.Wrap(client.ErrVersionConflict, err)
+	case cause == ErrClosedClient, stderrors.Is(cause, zk.ErrClosing):
+		return client.Wrap(client.ErrClosed, err)
+	}
+	return errors.Trace(err)
+}
+
+// IsRetryable reports errors after which an idempotent request may succeed
+// when sent again on the same or a reconnected session.
+func IsRetryable(err error) bool {
+	err = errors.Cause(err)
+	for _, e := range []error{zk.ErrConnectionClosed, zk.ErrSessionExpired, zk.ErrSessionMoved, zk.ErrNoServer} {
+		if err != nil && stderrors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotSent reports errors returned before the request could be applied.
+func IsNotSent(err error) bool {
+	err = errors.Cause(err)
+	return err != nil && stderrors.Is(err, zk.ErrNoServer)
+}
+
 func (c *Client) Mkdir(path string) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
-	log.Debugf("zkclient mkdir node %s", path)
+	c.log.Debugf("zkclient mkdir node %s", path)
 	err := c.shell(func(conn *zk.Conn) error {
 		return c.mkdir(conn, path)
 	})
 	if err != nil {
-		log.Debugf("zkclient mkdir node %s failed: %s", path, err)
+		c.log.Debugf("zkclient mkdir node %s failed: %s", path, err)
 		return err
 	}
-	log.Debugf("zkclient mkdir OK")
+	c.log.Debugf("zkclient mkdir OK")
 	return nil
 }
 
-func (c *Client) mkdir(conn *zk.Conn, path string) error {
+func (c *Client) mkdir(conn nodeCreator, path string) error {
 	if path == "" || path == "/" {
 		return nil
 	}
@@ -190,18 +430,18 @@ func (c *Client) Create(path string, data []byte) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
-	log.Debugf("zkclient create node %s", path)
+	c.log.Debugf("zkclient create node %s", path)
 	err := c.shell(func(conn *zk.Conn) error {
 		_, err := c.create(conn, path, data, 0)
 		return err
 	})
 	if err != nil {
-		log.Debugf("zkclient create node %s failed: %s", path, err)
+		c.log.Debugf("zkclient create node %s failed: %s", path, err)
 		return err
 	}
-	log.Debugf("zkclient create OK")
+	c.log.Debugf("zkclient create OK")
 	return nil
 }
 
@@ -209,19 +449,19 @@ func (c *Client) CreateInOrder(path string, data []byte) (string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return "", errors.Trace(ErrClosedClient)
+		return "", mapError(ErrClosedClient)
 	}
 	path = filepath.Join(path, "prefix_")
-	log.Debugf("zkclient create node %s", path)
+	c.log.Debugf("zkclient create node %s", path)
 	err := c.shell(func(conn *zk.Conn) error {
 		_, err := c.create(conn, path, data, zk.FlagSequence)
 		return err
 	})
 	if err != nil {
-		log.Debugf("zkclient create node %s failed: %s", path, err)
+		c.log.Debugf("zkclient create node %s failed: %s", path, err)
 		return "", err
 	}
-	log.Debugf("zkclient create OK")
+	c.log.Debugf("zkclient create OK")
 	return path, nil
 }
 
@@ -229,15 +469,16 @@ func (c *Client) CreateEphemeral(path string, data []byte) (<-chan struct{}, err
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 	var signal <-chan struct{}
-	log.Debugf("zkclient create-ephemeral node %s", path)
+	c.log.Debugf("zkclient create-ephemeral node %s", path)
 	err := c.shell(func(conn *zk.Conn) error {
 		p, err := c.create(conn, path, data, zk.FlagEphemeral)
 		if err != nil {
 			return err
 		}
+		c.ephemerals[p] = &ephemeral{data: data}
 		w, err := c.watch(conn, p)
 		if err != nil {
 			return err
@@ -246,14 +487,14 @@ func (c *Client) CreateEphemeral(path string, data []byte) (<-chan struct{}, err
 		return nil
 	})
 	if err != nil {
-		log.Debugf("zkclient create-ephemeral node %s failed: %s", path, err)
+		c.log.Debugf("zkclient create-ephemeral node %s failed: %s", path, err)
 		return nil, err
 	}
-	log.Debugf("zkclient create-ephemeral OK: %q", path)
+	c.log.Debugf("zkclient create-ephemeral OK: %q", path)
 	return signal, nil
 }
 
-func (c *Client) create(conn *zk.Conn, path string, data []byte, flag int32) (string, error) {
+func (c *Client) create(conn nodeCreator, path string, data []byte, flag int32) (string, error) {
 	if err := c.mkdir(conn, filepath.Dir(path)); err != nil {
 		return "", err
 	}
@@ -279,7 +520,7 @@ func (c *Client) watch(conn *zk.Conn, path string) (<-chan struct{}, error) {
 	go func() {
 		defer close(signal)
 		<-w
-		log.Debugf("zkclient watch node %s update", path)
+		c.log.Debugf("zkclient watch node %s update", path)
 	}()
 	return signal, nil
 }
@@ -288,17 +529,17 @@ func (c *Client) Update(path string, data []byte) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
-	log.Debugf("zkclient update node %s", path)
+	c.log.Debugf("zkclient update node %s", path)
 	err := c.shell(func(conn *zk.Conn) error {
 		return c.update(conn, path, data)
 	})
 	if err != nil {
-		log.Debugf("zkclient update node %s failed: %s", path, err)
+		c.log.Debugf("zkclient update node %s failed: %s", path, err)
 		return err
 	}
-	log.Debugf("zkclient update OK")
+	c.log.Debugf("zkclient update OK")
 	return nil
 }
 
@@ -322,9 +563,10 @@ func (c *Client) Delete(path string) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return errors.Trace(ErrClosedClient)
+		return mapError(ErrClosedClient)
 	}
-	log.Debugf("zkclient delete node %s", path)
+	c.log.Debugf("zkclient delete node %s", path)
+	delete(c.ephemerals, path)
 	err := c.shell(func(conn *zk.Conn) error {
 		err := conn.Delete(path, -1)
 		if err != nil && errors.NotEqual(err, zk.ErrNoNode) {
@@ -333,10 +575,10 @@ func (c *Client) Delete(path string) error {
 		return nil
 	})
 	if err != nil {
-		log.Debugf("zkclient delete node %s failed: %s", path, err)
+		c.log.Debugf("zkclient delete node %s failed: %s", path, err)
 		return err
 	}
-	log.Debugf("zkclient delete OK")
+	c.log.Debugf("zkclient delete OK")
 	return nil
 }
 
@@ -393,7 +635,7 @@ func (c *Client) Read(path string, must bool) ([]byte, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 	var data []byte
 	err := c.shell(func(conn *zk.Conn) error {
@@ -408,7 +650,7 @@ func (c *Client) Read(path string, must bool) ([]byte, error) {
 		return nil
 	})
 	if err != nil {
-		log.Debugf("zkclient read node %s failed: %s", path, err)
+		c.log.Debugf("zkclient read node %s failed: %s", path, err)
 		return nil, err
 	}
 	return data, nil
@@ -418,7 +660,7 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, errors.Trace(ErrClosedClient)
+		return nil, mapError(ErrClosedClient)
 	}
 	var paths []string
 	err := c.shell(func(conn *zk.Conn) error {
@@ -435,29 +677,115 @@ func (c *Client) List(path string, must bool) ([]string, error) {
 		return nil
 	})
 	if err != nil {
-		log.Debugf("zkclient list node %s failed: %s", path, err)
+		c.log.Debugf("zkclient list node %s failed: %s", path, err)
 		return nil, err
 	}
 	return paths, nil
 }
 
+func (c *Client) ReadMany(paths []string, must bool) ([][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, mapError(ErrClosedClient)
+	}
+	var data [][]byte
+	err := c.shell(func(conn *zk.Conn) error {
+		b, err := c.readMany(conn, paths, must)
+		if err != nil {
+			return err
+		}
+		data = b
+		return nil
+	})
+	if err != nil {
+		c.log.Debugf("zkclient read-many nodes failed: %s", err)
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *Client) ListWithValues(path string, must bool) (map[string][]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, mapError(ErrClosedClient)
+	}
+	var values map[string][]byte
+	err := c.shell(func(conn *zk.Conn) error {
+		nodes, _, err := conn.Children(path)
+		if err != nil {
+			if errors.Equal(err, zk.ErrNoNode) && !must {
+				return nil
+			}
+			return errors.Trace(err)
+		}
+		paths := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			paths = append(paths, filepath.Join(path, node))
+		}
+		// children may vanish between the two calls, skip them silently
+		data, err := c.readMany(conn, paths, false)
+		if err != nil {
+			return err
+		}
+		values = make(map[string][]byte, len(paths))
+		for i, p := range paths {
+			if data[i] != nil {
+				values[p] = data[i]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.log.Debugf("zkclient list-values node %s failed: %s", path, err)
+		return nil, err
+	}
+	return values, nil
+}
+
+// readMany pipelines the reads over the shared session instead of waiting
+// for each reply in turn.
+func (c *Client) readMany(conn *zk.Conn, paths []string, must bool) ([][]byte, error) {
+	data := make([][]byte, len(paths))
+	err := client.ParallelDo(len(paths), func(i int) error {
+		b, _, err := conn.Get(paths[i])
+		if err != nil {
+			if errors.Equal(err, zk.ErrNoNode) && !must {
+				return nil
+			}
+			return errors.Trace(err)
+		}
+		if b == nil {
+			b = []byte{}
+		}
+		data[i] = b
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 func (c *Client) CreateEphemeralInOrder(path string, data []byte) (<-chan struct{}, string, error) {
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, "", errors.Trace(ErrClosedClient)
+		return nil, "", mapError(ErrClosedClient)
 	}
 	if !strings.HasSuffix(path, "/") {
 		path += "/"
 	}
 	var signal <-chan struct{}
 	var node string
-	log.Debugf("zkclient create-ephemeral-inorder node %s", path)
+	c.log.Debugf("zkclient create-ephemeral-inorder node %s", path)
 	err := c.shell(func(conn *zk.Conn) error {
 		p, err := c.create(conn, path, data, zk.FlagEphemeral|zk.FlagSequence)
 		if err != nil {
 			return err
 		}
+		c.ephemerals[p] = &ephemeral{data: data, prefix: path}
 		w, err := c.watch(conn, p)
 		if err != nil {
 			return err
@@ -466,10 +794,10 @@ func (c *Client) CreateEphemeralInOrder(path string, data []byte) (<-chan struct
 		return nil
 	})
 	if err != nil {
-		log.Debugf("zkclient create-ephemeral-inorder node %s failed: %s", path, err)
+		c.log.Debugf("zkclient create-ephemeral-inorder node %s failed: %s", path, err)
 		return nil, "", err
 	}
-	log.Debugf("zkclient create-ephemeral-inorder OK, node = %s", node)
+	c.log.Debugf("zkclient create-ephemeral-inorder OK, node = %s", node)
 	return signal, node, nil
 }
 
@@ -480,11 +808,11 @@ func (c *Client) WatchInOrder(path string) (<-chan client.Event, []string, error
 	c.Lock()
 	defer c.Unlock()
 	if c.closed {
-		return nil, nil, errors.Trace(ErrClosedClient)
+		return nil, nil, mapError(ErrClosedClient)
 	}
 	var signal chan client.Event
 	var paths []string
-	log.Debugf("zkclient watch-inorder node %s", path)
+	c.log.Debugf("zkclient watch-inorder node %s", path)
 	err := c.shell(func(conn *zk.Conn) error {
 		nodes, _, w, err := conn.ChildrenW(path)
 		if err != nil {
@@ -495,21 +823,60 @@ func (c *Client) WatchInOrder(path string) (<-chan client.Event, []string, error
 			paths = append(paths, filepath.Join(path, node))
 		}
 		signal = make(chan client.Event, 1)
-		go func() {
+		go func(session <-chan struct{}) {
 			defer close(signal)
-			e := <-w
-			ev := client.Event{
-				Type: client.EventType(e.Type),
+			for {
+				e := <-w
+				if e.Type != zk.EventNotWatching {
+					signal <- client.Event{Type: client.EventType(e.Type)}
+					c.log.Debugf("zkclient watch-inorder node %s update", path)
+					return
+				}
+				// the session is gone, re-arm on the next one and only
+				// report a change if the children differ meanwhile
+				select {
+				case <-session:
+				case <-c.done:
+					signal <- client.Event{Type: client.EventNotWatching}
+					return
+				}
+				var changed bool
+				var err error
+				w, session, changed, err = c.rewatch(path, nodes)
+				switch {
+				case err != nil:
+					c.log.Debugf("zkclient watch-inorder node %s re-arm failed: %s", path, err)
+					signal <- client.Event{Type: client.EventSession}
+					return
+				case changed:
+					signal <- client.Event{Type: client.EventNodeChildrenChanged}
+					c.log.Debugf("zkclient watch-inorder node %s update", path)
+					return
+				}
+				c.log.Debugf("zkclient watch-inorder node %s re-armed", path)
 			}
-			signal <- ev
-			log.Debugf("zkclient watch-inorder node %s update", path)
-		}()
+		}(c.session)
 		return nil
 	})
 	if err != nil {
-		log.Debugf("zkclient watch-inorder node %s failed: %s", path, err)
+		c.log.Debugf("zkclient watch-inorder node %s failed: %s", path, err)
 		return nil, nil, err
 	}
-	log.Debugf("zkclient watch-inorder OK")
+	c.log.Debugf("zkclient watch-inorder OK")
 	return signal, paths, nil
 }
+
+func (c *Client) rewatch(path string, nodes []string) (<-chan zk.Event, <-chan struct{}, bool, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.closed {
+		return nil, nil, false, mapError(ErrClosedClient)
+	}
+	current, _, w, err := c.conn.ChildrenW(path)
+	if err != nil {
+		return nil, nil, false, mapError(errors.Trace(err))
+	}
+	sort.Strings(current)
+	changed := strings.Join(current, "/") != strings.Join(nodes, "/")
+	return w, c.session, changed, nil
+}
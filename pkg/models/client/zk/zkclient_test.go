@@ -0,0 +1,122 @@
+package zkclient
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IceFireDB/kit/pkg/models/client"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConn struct {
+	nodes    map[string]bool
+	seq      int
+	creates  int
+	onCreate func(path string)
+}
+
+func (f *fakeConn) Exists(path string) (bool, *zk.Stat, error) {
+	return f.nodes[path], nil, nil
+}
+
+func (f *fakeConn) Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	if flags&zk.FlagSequence != 0 {
+		f.seq++
+		path = fmt.Sprintf("%s%010d", path, f.seq)
+	}
+	if f.nodes[path] {
+		return "", zk.ErrNodeExists
+	}
+	if flags&zk.FlagEphemeral != 0 {
+		f.creates++
+	}
+	f.nodes[path] = true
+	if f.onCreate != nil {
+		f.onCreate(path)
+	}
+	return path, nil
+}
+
+func (f *fakeConn) Delete(path string, version int32) error {
+	if !f.nodes[path] {
+		return zk.ErrNoNode
+	}
+	delete(f.nodes, path)
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	c := &Client{
+		log:           log,
+		session:       make(chan struct{}),
+		sessionEvents: make(chan client.Event, 16),
+		ephemerals: map[string]*ephemeral{
+			"/proxy/proxy-1": {data: []byte("1")},
+		},
+	}
+	for i := 0; i < 8; i++ {
+		c.ephemerals[fmt.Sprintf("/actions/node_%010d", i)] = &ephemeral{prefix: "/actions/node_"}
+	}
+
+	// every session expiry re-creates each ephemeral exactly once
+	for i := 1; i <= 5; i++ {
+		conn := &fakeConn{nodes: make(map[string]bool), seq: i * 100}
+		session := c.session
+		c.recover(conn)
+
+		assert.Equal(t, 9, conn.creates)
+		assert.Len(t, c.ephemerals, 9)
+		for p := range c.ephemerals {
+			assert.True(t, conn.nodes[p], p)
+			assert.True(t, p == "/proxy/proxy-1" || strings.HasPrefix(p, "/actions/node_"), p)
+		}
+		_, open := <-session
+		assert.False(t, open)
+		assert.Equal(t, client.Event{Type: client.EventSession}, <-c.sessionEvents)
+	}
+}
+
+func TestRecoverDeleted(t *testing.T) {
+	c := &Client{
+		log:           log,
+		session:       make(chan struct{}),
+		sessionEvents: make(chan client.Event, 16),
+		ephemerals: map[string]*ephemeral{
+			"/proxy/proxy-1":           {data: []byte("1")},
+			"/actions/node_0000000001": {prefix: "/actions/node_"},
+		},
+	}
+	conn := &fakeConn{nodes: make(map[string]bool), seq: 100}
+	// Delete runs while the nodes are re-created
+	conn.onCreate = func(string) {
+		c.Lock()
+		delete(c.ephemerals, "/proxy/proxy-1")
+		delete(c.ephemerals, "/actions/node_0000000001")
+		c.Unlock()
+	}
+	c.recover(conn)
+
+	assert.Empty(t, c.ephemerals)
+	assert.Equal(t, map[string]bool{"/proxy": true, "/actions": true}, conn.nodes)
+}
+
+func TestShellKeepsSession(t *testing.T) {
+	dialAt := time.Now().Add(-time.Hour)
+	c := &Client{log: log, dialAt: dialAt, backoff: minResetBackoff}
+	for _, e := range []error{
+		fmt.Errorf("read /missing: %w", zk.ErrNoNode),
+		zk.ErrNodeExists,
+		zk.ErrBadVersion,
+		zk.ErrNoAuth,
+		zk.ErrNotEmpty,
+	} {
+		err := c.shell(func(conn *zk.Conn) error { return e })
+		assert.NotNil(t, err)
+		assert.Equal(t, dialAt, c.dialAt, "%s reset the connection", e)
+	}
+	err := c.shell(func(conn *zk.Conn) error { return fmt.Errorf("read: %w", zk.ErrNoNode) })
+	assert.True(t, client.Is(err, client.ErrNotFound))
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IceFireDB/kit/pkg/models/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDSN(t *testing.T) {
+	opts, err := ParseDSN("etcd://user:p%40ss@h1:2379,h2/ns?timeout=5s&ca=/etc/ca.pem")
+	assert.Nil(t, err)
+	assert.Equal(t, &ClientOptions{
+		Coordinator: "etcd",
+		AddrList:    "h1:2379,h2",
+		Auth:        "user:p@ss",
+		Timeout:     5 * time.Second,
+		Namespace:   "/ns",
+		TLS:         &client.TLSConfig{CAFile: "/etc/ca.pem"},
+	}, opts)
+
+	opts, err = ParseDSN("fs:///var/lib/icefire")
+	assert.Nil(t, err)
+	assert.Equal(t, &ClientOptions{Coordinator: "fs", AddrList: "/var/lib/icefire"}, opts)
+
+	// the order of the query does not matter
+	for _, query := range []string{"tls=true&server_name=zk", "server_name=zk&tls=true"} {
+		opts, err = ParseDSN("zk://h1?" + query)
+		assert.Nil(t, err)
+		assert.Equal(t, &client.TLSConfig{ServerName: "zk"}, opts.TLS)
+	}
+	for _, query := range []string{"tls=false&ca=/etc/ca.pem", "ca=/etc/ca.pem&tls=false", "tls=0&insecure_skip_verify=true"} {
+		_, err = ParseDSN("zk://h1?" + query)
+		assert.NotNil(t, err, query)
+	}
+	opts, err = ParseDSN("zk://h1?tls=false")
+	assert.Nil(t, err)
+	assert.Nil(t, opts.TLS)
+	opts, err = ParseDSN("zk://h1?tls=true")
+	assert.Nil(t, err)
+	assert.Equal(t, &client.TLSConfig{}, opts.TLS)
+
+	for _, query := range []string{"sasl=true", "sasl_mechanism=DIGEST-MD5", "user=admin"} {
+		_, err = ParseDSN("zk://h1?" + query)
+		if assert.NotNil(t, err, query) {
+			assert.Contains(t, err.Error(), ErrSASLNotSupported.Error())
+		}
+	}
+
+	_, err = ParseDSN("zk://h1?unknown=1")
+	assert.NotNil(t, err)
+	_, err = ParseDSN("h1:2181")
+	assert.NotNil(t, err)
+}
@@ -11,7 +11,7 @@ import (
 	"testing"
 	"time"
 
-	log "github.com/IceFireDB/kit/pkg/logger"
+	"github.com/IceFireDB/kit/pkg/logger"
 
 	"github.com/IceFireDB/kit/pkg/models/client"
 
@@ -26,7 +26,7 @@ var (
 )
 
 func init() {
-	log.Init("test")
+	logger.Init("test")
 }
 
 func getClient() client.Client {
@@ -4,23 +4,143 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
+	"sync"
 
 	"github.com/CodisLabs/codis/pkg/utils/errors"
-	"github.com/CodisLabs/codis/pkg/utils/log"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
-func jsonEncode(v interface{}) []byte {
-	b, err := json.MarshalIndent(v, "", "    ")
+// codecMagic starts every stored value that is not plain JSON and is
+// followed by the Marker of the codec that produced it. JSON values are
+// left unmarked so readers that predate codecs can still parse them.
+const codecMagic byte = 0xc1
+
+const (
+	MarkerJSON    byte = 0
+	MarkerMsgpack byte = 'm'
+)
+
+var ErrUnknownCodec = errors.New("unknown codec marker")
+
+// Codec serializes the models stored on the coordinator.
+type Codec interface {
+	// Marker identifies the codec in stored data, MarkerJSON means unmarked.
+	Marker() byte
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(b []byte, v interface{}) error
+}
+
+var (
+	JSONCodec       Codec = jsonCodec{}
+	JSONIndentCodec Codec = jsonCodec{indent: "    "}
+	MsgpackCodec    Codec = msgpackCodec{}
+)
+
+var codecs = struct {
+	sync.RWMutex
+	markers map[byte]Codec
+	current Codec
+}{
+	markers: map[byte]Codec{
+		MarkerJSON:    JSONCodec,
+		MarkerMsgpack: MsgpackCodec,
+	},
+	current: JSONIndentCodec,
+}
+
+// RegisterCodec makes c available for decoding values carrying its marker.
+func RegisterCodec(c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.markers[c.Marker()] = c
+}
+
+// SetCodec selects the codec used by Encode on every model. Values written
+// with any registered codec stay readable, so it can be switched at runtime.
+func SetCodec(c Codec) {
+	RegisterCodec(c)
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.current = c
+}
+
+func currentCodec() Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.current
+}
+
+func encode(v interface{}) ([]byte, error) {
+	return encodeWith(currentCodec(), v)
+}
+
+func encodeWith(c Codec, v interface{}) ([]byte, error) {
+	b, err := c.Marshal(v)
 	if err != nil {
-		log.PanicErrorf(err, "encode to json failed")
+		return nil, errors.Trace(err)
+	}
+	if c.Marker() == MarkerJSON {
+		return b, nil
 	}
-	return b
+	return append([]byte{codecMagic, c.Marker()}, b...), nil
 }
 
-func jsonDecode(v interface{}, b []byte) error {
-	if err := json.Unmarshal(b, v); err != nil {
+func decode(v interface{}, b []byte) error {
+	var c Codec = JSONCodec
+	if len(b) >= 2 && b[0] == codecMagic {
+		codecs.RLock()
+		c = codecs.markers[b[1]]
+		codecs.RUnlock()
+		if c == nil {
+			return errors.Errorf("%s: %q", ErrUnknownCodec, b[1])
+		}
+		b = b[2:]
+	}
+	if err := c.Unmarshal(b, v); err != nil {
 		return errors.Trace(err)
 	}
 	return nil
 }
+
+type jsonCodec struct {
+	indent string
+}
+
+func (jsonCodec) Marker() byte {
+	return MarkerJSON
+}
+
+func (c jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	if c.indent != "" {
+		return json.MarshalIndent(v, "", c.indent)
+	}
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marker() byte {
+	return MarkerMsgpack
+}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(b []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(b))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
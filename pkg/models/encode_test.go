@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodecs(t *testing.T) {
+	defer SetCodec(JSONIndentCodec)
+
+	slot := NewSlot(productName, 7)
+	slot.GroupId = 3
+	var stored [][]byte
+	for _, c := range []Codec{JSONCodec, JSONIndentCodec, MsgpackCodec} {
+		SetCodec(c)
+		b, err := slot.Encode()
+		assert.Nil(t, err)
+		stored = append(stored, b)
+	}
+
+	// values written by every codec stay readable whatever the current one is
+	for _, b := range stored {
+		var s Slot
+		assert.Nil(t, s.Decode(b))
+		assert.Equal(t, *slot, s)
+	}
+
+	var s Slot
+	assert.NotNil(t, s.Decode([]byte{codecMagic, 'x', '{', '}'}))
+}
@@ -33,12 +33,20 @@ type ServerGroup struct {
 	Servers     []Server `json:"servers"`
 }
 
-func (g *ServerGroup) Encode() []byte {
-	return jsonEncode(g)
+func (g *ServerGroup) Encode() ([]byte, error) {
+	return encode(g)
 }
 
-func (s *Server) Encode() []byte {
-	return jsonEncode(s)
+func (g *ServerGroup) Decode(b []byte) error {
+	return decode(g, b)
+}
+
+func (s *Server) Encode() ([]byte, error) {
+	return encode(s)
+}
+
+func (s *Server) Decode(b []byte) error {
+	return decode(s, b)
 }
 
 func (s Server) String() string {
@@ -57,7 +65,7 @@ func GetServer(zkConn zkhelper.Conn, zkPath string) (*Server, error) {
 		return nil, errors.Trace(err)
 	}
 	srv := Server{}
-	if err := json.Unmarshal(data, &srv); err != nil {
+	if err := srv.Decode(data); err != nil {
 		return nil, errors.Trace(err)
 	}
 	return &srv, nil
@@ -19,8 +19,12 @@ func NewLock() *Lock {
 	return &Lock{Hostname: hostname, Pid: pid}
 }
 
-func (t *Lock) Encode() []byte {
-	return jsonEncode(t)
+func (t *Lock) Encode() ([]byte, error) {
+	return encode(t)
+}
+
+func (t *Lock) Decode(b []byte) error {
+	return decode(t, b)
 }
 
 func (t *Lock) Name() string {
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	slotsDesc = prometheus.NewDesc(
+		"icefire_product_slots", "Slots of the product by status.",
+		[]string{"product", "status"}, nil)
+	groupsDesc = prometheus.NewDesc(
+		"icefire_product_groups", "Server groups of the product.",
+		[]string{"product"}, nil)
+	proxiesOnlineDesc = prometheus.NewDesc(
+		"icefire_product_proxies_online", "Online proxies of the product.",
+		[]string{"product"}, nil)
+)
+
+// DefaultStoreCollectorTTL is how long NewStoreCollector reuses a product
+// summary.
+const DefaultStoreCollectorTTL = 15 * time.Second
+
+type storeCollector struct {
+	store *Store
+	ttl   time.Duration
+
+	sync.Mutex
+	summary *ProductSummary
+	err     error
+	readAt  time.Time
+}
+
+// NewStoreCollector returns a collector reporting the product summary of s,
+// read from the coordinator at most once every DefaultStoreCollectorTTL.
+func NewStoreCollector(s *Store) prometheus.Collector {
+	return NewStoreCollectorWithTTL(s, DefaultStoreCollectorTTL)
+}
+
+// NewStoreCollectorWithTTL is NewStoreCollector reusing a summary for ttl,
+// scrapes waiting for a summary being read share it.
+func NewStoreCollectorWithTTL(s *Store, ttl time.Duration) prometheus.Collector {
+	return &storeCollector{store: s, ttl: ttl}
+}
+
+func (c *storeCollector) describeProduct() (*ProductSummary, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.readAt.IsZero() || time.Since(c.readAt) >= c.ttl {
+		c.summary, c.err = c.store.DescribeProduct()
+		c.readAt = time.Now()
+	}
+	return c.summary, c.err
+}
+
+func (c *storeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- slotsDesc
+	ch <- groupsDesc
+	ch <- proxiesOnlineDesc
+}
+
+func (c *storeCollector) Collect(ch chan<- prometheus.Metric) {
+	summary, err := c.describeProduct()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(slotsDesc, err)
+		return
+	}
+	for _, status := range []SlotStatus{
+		SLOT_STATUS_ONLINE, SLOT_STATUS_OFFLINE, SLOT_STATUS_MIGRATE, SLOT_STATUS_PRE_MIGRATE,
+	} {
+		ch <- prometheus.MustNewConstMetric(slotsDesc, prometheus.GaugeValue,
+			float64(summary.Slots[status]), summary.Name, string(status))
+	}
+	ch <- prometheus.MustNewConstMetric(groupsDesc, prometheus.GaugeValue,
+		float64(summary.Groups), summary.Name)
+	ch <- prometheus.MustNewConstMetric(proxiesOnlineDesc, prometheus.GaugeValue,
+		float64(summary.OnlineProxies), summary.Name)
+}
+
+// MetricsHandler serves the metrics of g in the Prometheus text format.
+func MetricsHandler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
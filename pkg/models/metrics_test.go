@@ -0,0 +1,51 @@
+package models
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/IceFireDB/kit/pkg/models/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := client.NewMetrics(reg)
+	assert.Nil(t, err)
+
+	c, err := NewClientWithOptions(&ClientOptions{Coordinator: "mem", Namespace: "/staging", Metrics: m})
+	assert.Nil(t, err)
+	defer c.Close()
+
+	s := NewStore(c, productName)
+	assert.Nil(t, s.CreateProduct(16))
+	assert.Nil(t, s.UpdateGroup(NewServerGroup(productName, 1)))
+	_, err = c.Read(GroupPath(productName, 2), true)
+	assert.NotNil(t, err)
+	assert.Nil(t, reg.Register(NewStoreCollector(s)))
+
+	w := httptest.NewRecorder()
+	MetricsHandler(reg).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	b, err := ioutil.ReadAll(w.Body)
+	assert.Nil(t, err)
+	body := string(b)
+
+	for _, line := range []string{
+		`icefire_coordinator_errors_total{backend="mem",kind="not_found",op="read",prefix="/icefire/` + productName + `"} 1`,
+		`icefire_product_slots{product="` + productName + `",status="offline"} 16`,
+		`icefire_product_groups{product="` + productName + `"} 1`,
+		`icefire_product_proxies_online{product="` + productName + `"} 0`,
+	} {
+		assert.True(t, strings.Contains(body, line), line)
+	}
+	assert.True(t, strings.Contains(body, `icefire_coordinator_request_duration_seconds_bucket{backend="mem",op="update"`))
+
+	// the summary is not read again within the ttl
+	assert.Nil(t, s.UpdateGroup(NewServerGroup(productName, 2)))
+	w = httptest.NewRecorder()
+	MetricsHandler(reg).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(t, w.Body.String(), `icefire_product_groups{product="`+productName+`"} 1`)
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"os"
+	"time"
+
+	"github.com/CodisLabs/codis/pkg/utils/errors"
+	"github.com/IceFireDB/kit/pkg/models/client"
+)
+
+var (
+	ErrProductExists        = errors.New("product already exists")
+	ErrProductNotFound      = errors.New("product not found")
+	ErrProductProxiesOnline = errors.New("product still has online proxies")
+	ErrProductLocked        = errors.New("product is locked")
+)
+
+// ProductSummary counts what a product holds on the coordinator.
+type ProductSummary struct {
+	Name          string             `json:"name"`
+	SchemaVersion int                `json:"schema_version"`
+	Slots         map[SlotStatus]int `json:"slots"`
+	Groups        int                `json:"groups"`
+	Servers       int                `json:"servers"`
+	Proxies       int                `json:"proxies"`
+	OnlineProxies int                `json:"online_proxies"`
+}
+
+func (s *Store) ProductExists() (bool, error) {
+	paths, err := s.client.List(s.ProductDir(), false)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	return len(paths) != 0, nil
+}
+
+// CreateProduct validates the product name and initializes totalSlotNum
+// offline slots for a product that doesn't exist yet. It holds the product
+// lock while it runs, so it fails with ErrProductLocked while someone else
+// holds it, another CreateProduct included.
+func (s *Store) CreateProduct(totalSlotNum int) (err error) {
+	span, cl := s.startSpan("CreateProduct", client.Attr("slots", totalSlotNum))
+	defer func() { span.End(err) }()
+	if err := ValidateProduct(s.product); err != nil {
+		return err
+	}
+	if totalSlotNum <= 0 {
+		return errors.Errorf("invalid slot number = %d", totalSlotNum)
+	}
+	if err := s.acquireProduct(); err != nil {
+		return err
+	}
+	defer s.releaseProduct()
+	paths, err := cl.List(s.ProductDir(), false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, p := range paths {
+		if p != s.LockPath() {
+			return errors.Errorf("%s: %s", ErrProductExists, s.product)
+		}
+	}
+	return s.InitSlotSet(s.product, totalSlotNum)
+}
+
+func (s *Store) ListProxy() (_ map[string]*ProxyInfo, err error) {
+	span, cl := s.startSpan("ListProxy")
+	defer func() { span.End(err) }()
+	values, err := client.ListWithValues(cl, s.ProxyDir(), false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	proxies := make(map[string]*ProxyInfo, len(values))
+	for _, b := range values {
+		p := &ProxyInfo{}
+		if err := p.Decode(b); err != nil {
+			return nil, err
+		}
+		proxies[p.Id] = p
+	}
+	return proxies, nil
+}
+
+func (s *Store) DescribeProduct() (_ *ProductSummary, err error) {
+	span, cl := s.startSpan("DescribeProduct")
+	defer func() { span.End(err) }()
+	exists, err := s.ProductExists()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.Errorf("%s: %s", ErrProductNotFound, s.product)
+	}
+	summary := &ProductSummary{
+		Name:  s.product,
+		Slots: make(map[SlotStatus]int),
+	}
+
+	schema, err := s.LoadSchema()
+	if err != nil {
+		return nil, err
+	}
+	summary.SchemaVersion = schema.Version
+
+	slots, err := s.Slots()
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range slots {
+		summary.Slots[slot.State.Status]++
+	}
+
+	groups, err := s.ListGroup()
+	if err != nil {
+		return nil, err
+	}
+	summary.Groups = len(groups)
+
+	servers, err := cl.List(s.ServerDir(), false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	summary.Servers = len(servers)
+
+	proxies, err := s.ListProxy()
+	if err != nil {
+		return nil, err
+	}
+	summary.Proxies = len(proxies)
+	for _, p := range proxies {
+		if p.State == PROXY_STATE_ONLINE {
+			summary.OnlineProxies++
+		}
+	}
+	return summary, nil
+}
+
+// DeleteProduct removes every node of the product. It holds the product
+// lock while it runs, so it fails with ErrProductLocked while a dashboard or
+// a migration holds it, and it refuses to run while any proxy of the product
+// is online, mark them offline first.
+func (s *Store) DeleteProduct() (err error) {
+	span, cl := s.startSpan("DeleteProduct")
+	defer func() { span.End(err) }()
+	if err := s.acquireProduct(); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			s.releaseProduct()
+		}
+	}()
+	proxies, err := s.ListProxy()
+	if err != nil {
+		return err
+	}
+	for _, p := range proxies {
+		if p.State == PROXY_STATE_ONLINE {
+			return errors.Errorf("%s: %s", ErrProductProxiesOnline, p.Id)
+		}
+	}
+	children, err := cl.List(s.ProductDir(), false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// the lock goes last, nobody can take it while the tree is half deleted
+	for _, child := range children {
+		if child == s.LockPath() {
+			continue
+		}
+		if err := deleteRecursive(cl, child); err != nil {
+			return err
+		}
+	}
+	if err := cl.Delete(s.LockPath()); err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(cl.Delete(s.ProductDir()))
+}
+
+// acquireProduct takes the product lock for CreateProduct and DeleteProduct.
+func (s *Store) acquireProduct() error {
+	hostname, _ := os.Hostname()
+	err := s.Acquire(&Topom{
+		ProductName: s.product,
+		StartTime:   time.Now().String(),
+		Pid:         os.Getpid(),
+		Sys:         hostname,
+	})
+	if client.Is(err, client.ErrExists) {
+		return errors.Errorf("%s: %s", ErrProductLocked, s.product)
+	}
+	return err
+}
+
+func (s *Store) releaseProduct() {
+	if err := s.Release(); err != nil {
+		s.log.WithError(err).Warnf("release lock of product %s failed", s.product)
+	}
+}
+
+func deleteRecursive(c client.Client, path string) error {
+	children, err := c.List(path, false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, child := range children {
+		if err := deleteRecursive(c, child); err != nil {
+			return err
+		}
+	}
+	return errors.Trace(c.Delete(path))
+}
@@ -27,8 +27,12 @@ type ProxyInfo struct {
 }
 
 
-func (p *ProxyInfo) Encode() []byte {
-	return jsonEncode(p)
+func (p *ProxyInfo) Encode() ([]byte, error) {
+	return encode(p)
+}
+
+func (p *ProxyInfo) Decode(b []byte) error {
+	return decode(p, b)
 }
 
 func (p ProxyInfo) Ops() (int64, error) {
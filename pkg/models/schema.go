@@ -0,0 +1,223 @@
+package models
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/CodisLabs/codis/pkg/utils/errors"
+	"github.com/IceFireDB/kit/pkg/logger"
+	"github.com/IceFireDB/kit/pkg/models/client"
+)
+
+// SchemaVersion is the layout version written by this package. Products
+// created before versioning existed have no schema node and are version 0.
+const SchemaVersion = 1
+
+var ErrSchemaTooNew = errors.New("product schema is newer than supported")
+
+// Migration upgrades every node of a product by exactly one version. It must
+// only add or normalize fields so readers built for the older version keep
+// working while the rewrite is in progress.
+type Migration func(s *Store) error
+
+var migrations = struct {
+	sync.RWMutex
+	fns map[int]Migration
+}{
+	fns: map[int]Migration{
+		0: migrateToV1,
+	},
+}
+
+// RegisterMigration installs fn as the upgrade from version to version+1.
+func RegisterMigration(version int, fn Migration) {
+	migrations.Lock()
+	defer migrations.Unlock()
+	migrations.fns[version] = fn
+}
+
+func migration(version int) Migration {
+	migrations.RLock()
+	defer migrations.RUnlock()
+	return migrations.fns[version]
+}
+
+type Schema struct {
+	Version int    `json:"version"`
+	Ts      string `json:"ts"`
+}
+
+func (m *Schema) Encode() ([]byte, error) {
+	return encode(m)
+}
+
+func (m *Schema) Decode(b []byte) error {
+	return decode(m, b)
+}
+
+func SchemaPath(product string) string {
+	return Root(BaseDir).SchemaPath(product)
+}
+
+func (s *Store) SchemaPath() string {
+	return s.root.SchemaPath(s.product)
+}
+
+func (s *Store) LoadSchema() (_ *Schema, err error) {
+	span, cl := s.startSpan("LoadSchema")
+	defer func() { span.End(err) }()
+	b, err := cl.Read(s.SchemaPath(), false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	m := &Schema{}
+	if b == nil {
+		return m, nil
+	}
+	if err := m.Decode(b); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *Store) updateSchema(version int) error {
+	m := &Schema{
+		Version: version,
+		Ts:      strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	b, err := m.Encode()
+	if err != nil {
+		return err
+	}
+	return errors.Trace(s.client.Update(s.SchemaPath(), b))
+}
+
+// Migrate upgrades the product one version at a time up to SchemaVersion,
+// recording the version after every step so an interrupted run resumes where
+// it stopped. Callers should hold the product lock.
+func (s *Store) Migrate() (err error) {
+	span, _ := s.startSpan("Migrate")
+	defer func() { span.End(err) }()
+	m, err := s.LoadSchema()
+	if err != nil {
+		return err
+	}
+	if m.Version > SchemaVersion {
+		return errors.Errorf("%s: %d > %d", ErrSchemaTooNew, m.Version, SchemaVersion)
+	}
+	for v := m.Version; v < SchemaVersion; v++ {
+		fn := migration(v)
+		if fn == nil {
+			return errors.Errorf("no migration registered from schema version %d", v)
+		}
+		s.log.WithFields(logger.Fields{
+			"product": s.product,
+			"from":    v,
+			"to":      v + 1,
+		}).Info("migrate product schema")
+		if err := fn(s); err != nil {
+			return errors.Trace(err)
+		}
+		if err := s.updateSchema(v + 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateToV1 rewrites the slots, groups, servers, proxies and actions of
+// the product as plain JSON, see rewrite. It also fills the defaults NewSlot and
+// NewServer set on nodes written by tools that predate them: a parsable
+// LastOpTs, a reset migrate status on slots that are not migrating, and the
+// group id of every server that is a member of a group. The lock and the
+// registered cli nodes are short lived and left as they are.
+func migrateToV1(s *Store) error {
+	slots, err := s.Slots()
+	if err != nil {
+		return err
+	}
+	for i := range slots {
+		slot := &slots[i]
+		if _, err := strconv.ParseInt(slot.State.LastOpTs, 10, 64); err != nil {
+			slot.State.LastOpTs = "0"
+		}
+		if slot.State.Status != SLOT_STATUS_MIGRATE && slot.State.Status != SLOT_STATUS_PRE_MIGRATE {
+			slot.State.MigrateStatus = SlotMigrateStatus{From: INVALID_ID, To: INVALID_ID}
+		}
+		if err := s.rewrite(s.SlotPath(slot.Id), slot); err != nil {
+			return err
+		}
+	}
+
+	groups, err := s.ListGroup()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := s.rewrite(s.GroupPath(g.Id), g); err != nil {
+			return err
+		}
+	}
+
+	values, err := client.ListWithValues(s.client, s.ServerDir(), false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, b := range values {
+		server := &Server{}
+		if err := server.Decode(b); err != nil {
+			return err
+		}
+		for _, g := range groups {
+			for _, member := range g.Servers {
+				if member.Addr == server.Addr {
+					server.GroupId = g.Id
+				}
+			}
+		}
+		if err := s.rewrite(s.ServerPath(server.Addr), server); err != nil {
+			return err
+		}
+	}
+
+	proxies, err := s.ListProxy()
+	if err != nil {
+		return err
+	}
+	for _, p := range proxies {
+		if err := s.rewrite(s.ProxyPath(p.Id), p); err != nil {
+			return err
+		}
+	}
+
+	nodes, err := s.client.List(s.ActionDir(), false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, node := range nodes {
+		b, err := s.client.Read(node, true)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		a := &Action{}
+		if err := a.Decode(b); err != nil {
+			return err
+		}
+		if err := s.rewrite(node, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewrite writes v at path without emitting an action. It writes JSON
+// whatever the current codec is: a migration must leave the product readable
+// by the readers that predate codecs.
+func (s *Store) rewrite(path string, v interface{}) error {
+	b, err := encodeWith(JSONIndentCodec, v)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	return errors.Trace(s.client.Update(path, b))
+}
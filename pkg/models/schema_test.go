@@ -0,0 +1,114 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/CodisLabs/codis/pkg/utils/errors"
+	memclient "github.com/IceFireDB/kit/pkg/models/client/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+// setMigration replaces the migration from version for the test.
+func setMigration(t *testing.T, version int, fn Migration) {
+	old := migration(version)
+	RegisterMigration(version, fn)
+	t.Cleanup(func() { RegisterMigration(version, old) })
+}
+
+func TestMigrate(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		version int // stored before Migrate, -1 for no schema node
+		fail    bool
+		calls   int
+		want    int
+		wantErr string
+	}{
+		{name: "unversioned", version: -1, calls: 1, want: SchemaVersion},
+		{name: "current", version: SchemaVersion, want: SchemaVersion},
+		{name: "failing", version: -1, fail: true, calls: 1, want: 0, wantErr: "boom"},
+		{name: "too new", version: SchemaVersion + 1, want: SchemaVersion + 1, wantErr: ErrSchemaTooNew.Error()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := memclient.New()
+			defer c.Close()
+			s := NewStore(c, productName)
+			if tt.version >= 0 {
+				assert.Nil(t, s.updateSchema(tt.version))
+			}
+
+			var calls int
+			setMigration(t, 0, func(s *Store) error {
+				calls++
+				if tt.fail {
+					return errors.New("boom")
+				}
+				return nil
+			})
+
+			err := s.Migrate()
+			if tt.wantErr != "" {
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+			} else {
+				assert.Nil(t, err)
+				// running it again is a no-op
+				assert.Nil(t, s.Migrate())
+			}
+			assert.Equal(t, tt.calls, calls)
+			m, err := s.LoadSchema()
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, m.Version)
+		})
+	}
+}
+
+func TestMigrateToV1(t *testing.T) {
+	defer SetCodec(JSONIndentCodec)
+
+	c := memclient.New()
+	defer c.Close()
+	s := NewStore(c, productName)
+
+	// nodes written before versioning, as plain JSON without defaults
+	legacy := map[string]string{
+		s.SlotPath(0):                  `{"product_name":"` + productName + `","id":0,"group_id":1,"state":{"status":"online","last_op_ts":""}}`,
+		s.GroupPath(1):                 `{"id":1,"product_name":"` + productName + `","servers":[{"addr":"127.0.0.1:6379","type":"master"}]}`,
+		s.ServerPath("127.0.0.1:6379"): `{"addr":"127.0.0.1:6379","type":"master"}`,
+		s.ProxyPath("proxy-1"):         `{"id":"proxy-1","state":"offline"}`,
+	}
+	for path, v := range legacy {
+		assert.Nil(t, c.Update(path, []byte(v)))
+	}
+	SetCodec(MsgpackCodec)
+	assert.Nil(t, s.EmitAction(ACTION_TYPE_SLOT_CHANGED, NewSlot(productName, 0), ""))
+	assert.Nil(t, s.Migrate())
+
+	slot, err := s.GetSlot(0, true)
+	assert.Nil(t, err)
+	assert.Equal(t, "0", slot.State.LastOpTs)
+	assert.Equal(t, SlotMigrateStatus{From: INVALID_ID, To: INVALID_ID}, slot.State.MigrateStatus)
+	server, err := s.GetServer("127.0.0.1:6379", true)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, server.GroupId)
+
+	// every node is rewritten as plain JSON, even the msgpack action
+	paths, err := c.List(s.ActionDir(), true)
+	assert.Nil(t, err)
+	assert.Len(t, paths, 1)
+	for path := range legacy {
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		b, err := c.Read(path, true)
+		assert.Nil(t, err)
+		var v map[string]interface{}
+		assert.Nil(t, json.Unmarshal(b, &v), path)
+	}
+
+	_, err = s.GetActionWithSeq(strings.TrimPrefix(paths[0], s.ActionDir()+"/"))
+	assert.Nil(t, err)
+}
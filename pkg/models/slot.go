@@ -5,8 +5,10 @@ package models
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/CodisLabs/codis/pkg/utils/errors"
+	"github.com/IceFireDB/kit/pkg/models/client"
 )
 
 type SlotStatus string
@@ -69,8 +71,12 @@ func NewSlot(productName string, id int) *Slot {
 	}
 }
 
-func (s *Slot) Encode() []byte {
-	return jsonEncode(s)
+func (s *Slot) Encode() ([]byte, error) {
+	return encode(s)
+}
+
+func (s *Slot) Decode(b []byte) error {
+	return decode(s, b)
 }
 
 func (s *Store) GetMigratingSlots() ([]Slot, error) {
@@ -89,21 +95,24 @@ func (s *Store) GetMigratingSlots() ([]Slot, error) {
 	return migrateSlots, nil
 }
 
-func (s *Store) Slots() ([]Slot, error) {
-	slotPath := SlotDir(s.product)
-	children, err := s.client.List(slotPath, false)
+func (s *Store) Slots() (_ []Slot, err error) {
+	span, cl := s.startSpan("Slots")
+	defer func() { span.End(err) }()
+	values, err := client.ListWithValues(cl, s.SlotDir(), false)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
+	children := make([]string, 0, len(values))
+	for p := range values {
+		children = append(children, p)
+	}
+	sort.Strings(children)
+
 	var slots []Slot
 	for _, p := range children {
-		data, err := s.client.Read(p, true)
-		if err != nil {
-			return nil, errors.Trace(err)
-		}
 		slot := Slot{}
-		if err := json.Unmarshal(data, &slot); err != nil {
+		if err := slot.Decode(values[p]); err != nil {
 			return nil, errors.Trace(err)
 		}
 		slots = append(slots, slot)
@@ -112,12 +121,14 @@ func (s *Store) Slots() ([]Slot, error) {
 	return slots, nil
 }
 
-func (s *Store) SetMigrateStatus(slot *Slot, fromGroup, toGroup int) error {
+func (s *Store) SetMigrateStatus(slot *Slot, fromGroup, toGroup int) (err error) {
+	span, _ := s.startSpan("SetMigrateStatus", client.Attr("slot", slot.Id), client.Attr("from", fromGroup), client.Attr("to", toGroup))
+	defer func() { span.End(err) }()
 	if fromGroup < 0 || toGroup < 0 {
 		return errors.Errorf("invalid group id, from %d, to %d", fromGroup, toGroup)
 	}
 	// wait until all proxy confirmed
-	err := s.NewAction(ACTION_TYPE_SLOT_PREMIGRATE, slot, "", true)
+	err = s.EmitAction(ACTION_TYPE_SLOT_PREMIGRATE, slot, "")
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -5,94 +5,243 @@
 package models
 
 import (
-	"encoding/json"
 	"fmt"
 	"path"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/CodisLabs/codis/pkg/utils/errors"
+	"github.com/IceFireDB/kit/pkg/logger"
 	"github.com/IceFireDB/kit/pkg/models/client"
 )
 
+// BaseDir is the default root products are stored under.
 const BaseDir = "/icefire"
 
 var ErrGroupMasterNotFound = errors.New("group master not found")
 
+var log logger.Logger = logger.NewLogger("store")
+
+// Root is a coordinator directory holding products. Environments sharing a
+// coordinator use different roots so they can be isolated by ACL.
+type Root string
+
+func (r Root) ProductDir(product string) string {
+	return path.Join(string(r), product)
+}
+
+func (r Root) ActionDir(product string) string {
+	return path.Join(string(r), product, "actions")
+}
+
+func (r Root) ActionPath(product string, seq string) string {
+	return path.Join(string(r), product, "actions", seq)
+}
+
+func (r Root) LockPath(product string) string {
+	return path.Join(string(r), product, "pd")
+}
+
+func (r Root) CliDir(product string) string {
+	return path.Join(string(r), product, "living-cli-config")
+}
+
+func (r Root) CliPath(product string, name string) string {
+	return path.Join(string(r), product, "living-cli-config", name)
+}
+
+func (r Root) ProxyDir(product string) string {
+	return path.Join(string(r), product, "proxy")
+}
+
+func (r Root) ProxyPath(product string, id string) string {
+	return path.Join(string(r), product, "proxy", id)
+}
+
+func (r Root) SlotDir(product string) string {
+	return path.Join(string(r), product, "slots")
+}
+
+func (r Root) SlotPath(product string, sid int) string {
+	return path.Join(string(r), product, "slots", fmt.Sprintf("slot-%04d", sid))
+}
+
+func (r Root) GroupDir(product string) string {
+	return path.Join(string(r), product, "group")
+}
+
+func (r Root) ServerDir(product string) string {
+	return path.Join(string(r), product, "server")
+}
+
+func (r Root) GroupPath(product string, gid int) string {
+	return path.Join(string(r), product, "group", fmt.Sprintf("group-%04d", gid))
+}
+
+func (r Root) ServerPath(product string, addr string) string {
+	return path.Join(string(r), product, "server", fmt.Sprintf("server-%s", addr))
+}
+
+func (r Root) SchemaPath(product string) string {
+	return path.Join(string(r), product, "schema")
+}
+
 func ProductDir(product string) string {
-	return path.Join(BaseDir, product)
+	return Root(BaseDir).ProductDir(product)
 }
 
 func GetWatchActionDir(product string) string {
-	return path.Join(BaseDir, product, "actions")
+	return Root(BaseDir).ActionDir(product)
 }
 
 func ActionPath(product string, seq string) string {
-	return path.Join(BaseDir, product, "actions", seq)
+	return Root(BaseDir).ActionPath(product, seq)
 }
 
 func LockPath(product string) string {
-	return path.Join(BaseDir, product, "pd")
+	return Root(BaseDir).LockPath(product)
 }
 
 func CliDir(product string) string {
-	return path.Join(BaseDir, product, "living-cli-config")
+	return Root(BaseDir).CliDir(product)
 }
 
 func CliPath(product string, name string) string {
-	return path.Join(BaseDir, product, "living-cli-config", name)
+	return Root(BaseDir).CliPath(product, name)
 }
 
 func ProxyDir(product string) string {
-	return path.Join(BaseDir, product, "proxy")
+	return Root(BaseDir).ProxyDir(product)
 }
 
 func ProxyPath(product string, id string) string {
-	return path.Join(BaseDir, product, "proxy", id)
+	return Root(BaseDir).ProxyPath(product, id)
 }
 
 func SlotDir(product string) string {
-	return path.Join(BaseDir, product, "slots")
+	return Root(BaseDir).SlotDir(product)
 }
 
 func SlotPath(product string, sid int) string {
-	return path.Join(BaseDir, product, "slots", fmt.Sprintf("slot-%04d", sid))
+	return Root(BaseDir).SlotPath(product, sid)
 }
 
 func GroupDir(product string) string {
-	return path.Join(BaseDir, product, "group")
+	return Root(BaseDir).GroupDir(product)
 }
 
 func ServerDir(product string) string {
-	return path.Join(BaseDir, product, "server")
+	return Root(BaseDir).ServerDir(product)
 }
 
 func GroupPath(product string, gid int) string {
-	return path.Join(BaseDir, product, "group", fmt.Sprintf("group-%04d", gid))
+	return Root(BaseDir).GroupPath(product, gid)
 }
 
 func ServerPath(product string, addr string) string {
-	return path.Join(BaseDir, product, "server", fmt.Sprintf("server-%s", addr))
+	return Root(BaseDir).ServerPath(product, addr)
 }
 
 func LoadTopom(client client.Client, product string, must bool) (*Topom, error) {
-	b, err := client.Read(LockPath(product), must)
+	return loadTopom(client, LockPath(product), must)
+}
+
+func loadTopom(client client.Client, lockPath string, must bool) (*Topom, error) {
+	b, err := client.Read(lockPath, must)
 	if err != nil || b == nil {
 		return nil, err
 	}
 	t := &Topom{}
-	if err := jsonDecode(t, b); err != nil {
+	if err := t.Decode(b); err != nil {
 		return nil, err
 	}
 	return t, nil
 }
 
+// ListProducts returns the names of the products stored under root.
+func ListProducts(client client.Client, root string) ([]string, error) {
+	root = path.Clean(root)
+	paths, err := client.List(root, false)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// etcd lists every key below root, keep the first level only
+	seen := make(map[string]bool)
+	var products []string
+	for _, p := range paths {
+		name := strings.SplitN(strings.TrimPrefix(p, root+"/"), "/", 2)[0]
+		if name != "" && !seen[name] {
+			seen[name] = true
+			products = append(products, name)
+		}
+	}
+	sort.Strings(products)
+	return products, nil
+}
+
 type Store struct {
 	client  client.Client
 	product string
+	root    Root
+	tracer  atomic.Value // tracerHolder
+	log     logger.Logger
+
+	// mu serializes the methods reading a node to decide which action its
+	// write emits, across processes the product lock does.
+	mu sync.Mutex
+}
+
+// ActionError is returned by UpdateServer, DeleteServer and UpdateProxy when
+// the node was written but the action announcing the change could not be
+// created. The write is not rolled back and a retry finds nothing changed,
+// so callers should emit the action again with EmitAction.
+type ActionError struct {
+	Type ActionType
+	Err  error
+}
+
+func (e *ActionError) Error() string {
+	return fmt.Sprintf("node written, emit %s action failed: %s", e.Type, e.Err)
+}
+
+func (e *ActionError) Unwrap() error {
+	return e.Err
+}
+
+// emitAfterWrite creates an action for a node already written.
+func (s *Store) emitAfterWrite(t ActionType, target interface{}) error {
+	if err := s.EmitAction(t, target, ""); err != nil {
+		return &ActionError{Type: t, Err: err}
+	}
+	return nil
 }
 
 func NewStore(client client.Client, product string) *Store {
-	return &Store{client, product}
+	return NewStoreWithRoot(client, BaseDir, product)
+}
+
+// NewStoreWithRoot stores product under root instead of BaseDir.
+func NewStoreWithRoot(c client.Client, root string, product string) *Store {
+	return NewStoreWithLogger(c, root, product, nil)
+}
+
+// NewStoreWithLogger is NewStoreWithRoot writing the log of the store to l,
+// or to the "store" scope when l is nil.
+func NewStoreWithLogger(c client.Client, root string, product string, l logger.Logger) *Store {
+	if l == nil {
+		l = log
+	}
+	s := &Store{
+		client:  c,
+		product: product,
+		root:    Root(path.Clean("/" + root)),
+		log:     l,
+	}
+	s.SetTracer(nil)
+	return s
 }
 
 func (s *Store) Close() error {
@@ -103,52 +252,94 @@ func (s *Store) Client() client.Client {
 	return s.client
 }
 
+// tracerHolder gives the tracers stored in Store.tracer a single concrete type.
+type tracerHolder struct {
+	client.Tracer
+}
+
+// SetTracer makes the operations of s start spans on t, named
+// store.<Method>, or stops tracing them when t is nil. It is safe to call
+// while s is in use. Wrap the client with client.WithTracing to trace the
+// coordinator calls they are made of.
+func (s *Store) SetTracer(t client.Tracer) {
+	if t == nil {
+		t = client.NoopTracer
+	}
+	s.tracer.Store(tracerHolder{t})
+}
+
+// startSpan starts the span of op with the client to make its calls with, so
+// their spans are children of it.
+func (s *Store) startSpan(op string, attrs ...client.Attribute) (client.Span, client.Client) {
+	span := s.tracer.Load().(tracerHolder).Start(nil, "store."+op, append(attrs, client.Attr("product", s.product))...)
+	return span, client.WithParentSpan(s.client, span)
+}
+
+func (s *Store) Root() string {
+	return string(s.root)
+}
+
+// ListProducts returns the names of the products sharing the root of s.
+func (s *Store) ListProducts() (_ []string, err error) {
+	span, cl := s.startSpan("ListProducts")
+	defer func() { span.End(err) }()
+	return ListProducts(cl, string(s.root))
+}
+
+func (s *Store) ProductDir() string {
+	return s.root.ProductDir(s.product)
+}
+
+func (s *Store) ActionDir() string {
+	return s.root.ActionDir(s.product)
+}
+
 func (s *Store) LockPath() string {
-	return LockPath(s.product)
+	return s.root.LockPath(s.product)
 }
 
 func (s *Store) SlotDir() string {
-	return SlotDir(s.product)
+	return s.root.SlotDir(s.product)
 }
 
 func (s *Store) SlotPath(sid int) string {
-	return SlotPath(s.product, sid)
+	return s.root.SlotPath(s.product, sid)
 }
 
 func (s *Store) ProxyDir() string {
-	return ProxyDir(s.product)
+	return s.root.ProxyDir(s.product)
 }
 
 func (s *Store) ProxyPath(id string) string {
-	return ProxyPath(s.product, id)
+	return s.root.ProxyPath(s.product, id)
 }
 
 func (s *Store) CliDir() string {
-	return CliDir(s.product)
+	return s.root.CliDir(s.product)
 }
 
 func (s *Store) CliPath(name string) string {
-	return CliPath(s.product, name)
+	return s.root.CliPath(s.product, name)
 }
 
 func (s *Store) GroupDir() string {
-	return GroupDir(s.product)
+	return s.root.GroupDir(s.product)
 }
 
 func (s *Store) GroupPath(gid int) string {
-	return GroupPath(s.product, gid)
+	return s.root.GroupPath(s.product, gid)
 }
 
 func (s *Store) ServerDir() string {
-	return ServerDir(s.product)
+	return s.root.ServerDir(s.product)
 }
 
 func (s *Store) ActionPath(seq string) string {
-	return ActionPath(s.product, seq)
+	return s.root.ActionPath(s.product, seq)
 }
 
 func (s *Store) ServerPath(addr string) string {
-	return ServerPath(s.product, addr)
+	return s.root.ServerPath(s.product, addr)
 }
 
 func (s *Store) DeletePath(path string) error {
@@ -156,53 +347,103 @@ func (s *Store) DeletePath(path string) error {
 }
 
 func (s *Store) Lock() error {
-	return s.client.Create(s.LockPath(), NewLock().Encode())
+	b, err := NewLock().Encode()
+	if err != nil {
+		return err
+	}
+	return s.client.Create(s.LockPath(), b)
 }
 
 func (s *Store) UnLock() error {
 	return s.client.Delete(s.LockPath())
 }
 
-func (s *Store) Acquire(topom *Topom) error {
-	return s.client.Create(s.LockPath(), topom.Encode())
+func (s *Store) Acquire(topom *Topom) (err error) {
+	span, cl := s.startSpan("Acquire")
+	defer func() { span.End(err) }()
+	b, err := topom.Encode()
+	if err != nil {
+		return err
+	}
+	return cl.Create(s.LockPath(), b)
 }
 
-func (s *Store) Release() error {
-	return s.client.Delete(s.LockPath())
+func (s *Store) Release() (err error) {
+	span, cl := s.startSpan("Release")
+	defer func() { span.End(err) }()
+	return cl.Delete(s.LockPath())
 }
 
-func (s *Store) LoadTopom(must bool) (*Topom, error) {
-	return LoadTopom(s.client, s.product, must)
+func (s *Store) LoadTopom(must bool) (_ *Topom, err error) {
+	span, cl := s.startSpan("LoadTopom")
+	defer func() { span.End(err) }()
+	return loadTopom(cl, s.LockPath(), must)
 }
 
-func (s *Store) LoadProxy(id string) (*ProxyInfo, error) {
-	data, err := s.client.Read(s.ProxyPath(id), true)
+func (s *Store) LoadProxy(id string) (_ *ProxyInfo, err error) {
+	span, cl := s.startSpan("LoadProxy", client.Attr("proxy", id))
+	defer func() { span.End(err) }()
+	data, err := cl.Read(s.ProxyPath(id), true)
 	if err != nil || data == nil {
 		return nil, err
 	}
 	var p ProxyInfo
-	if err := json.Unmarshal(data, &p); err != nil {
+	if err := p.Decode(data); err != nil {
 		return nil, err
 	}
 
 	return &p, nil
 }
 
-func (s *Store) UpdateProxy(proxyInfo *ProxyInfo) error {
-	return s.client.Update(s.ProxyPath(proxyInfo.Id), proxyInfo.Encode())
+func (s *Store) UpdateProxy(proxyInfo *ProxyInfo) (err error) {
+	span, cl := s.startSpan("UpdateProxy", client.Attr("proxy", proxyInfo.Id))
+	defer func() { span.End(err) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.loadProxy(proxyInfo.Id)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	b, err := proxyInfo.Encode()
+	if err != nil {
+		return err
+	}
+	if err := cl.Update(s.ProxyPath(proxyInfo.Id), b); err != nil {
+		return errors.Trace(err)
+	}
+	if old == nil || old.State != proxyInfo.State {
+		return s.emitAfterWrite(ACTION_TYPE_PROXY_STATE_CHANGED, proxyInfo)
+	}
+	return nil
 }
 
-func (s *Store) DeleteProxy(id string) error {
-	return s.client.Delete(s.ProxyPath(id))
+func (s *Store) loadProxy(id string) (*ProxyInfo, error) {
+	data, err := s.client.Read(s.ProxyPath(id), false)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var p ProxyInfo
+	if err := p.Decode(data); err != nil {
+		return nil, err
+	}
+	return &p, nil
 }
 
-func (s *Store) GetSlot(sid int, must bool) (*Slot, error) {
-	data, err := s.client.Read(s.SlotPath(sid), must)
+func (s *Store) DeleteProxy(id string) (err error) {
+	span, cl := s.startSpan("DeleteProxy", client.Attr("proxy", id))
+	defer func() { span.End(err) }()
+	return cl.Delete(s.ProxyPath(id))
+}
+
+func (s *Store) GetSlot(sid int, must bool) (_ *Slot, err error) {
+	span, cl := s.startSpan("GetSlot", client.Attr("slot", sid))
+	defer func() { span.End(err) }()
+	data, err := cl.Read(s.SlotPath(sid), must)
 	if err != nil || data == nil {
 		return nil, err
 	}
 	var slot Slot
-	if err := json.Unmarshal(data, &slot); err != nil {
+	if err := slot.Decode(data); err != nil {
 		return nil, err
 	}
 
@@ -216,11 +457,15 @@ func (s *Store) InitSlotSet(productName string, totalSlotNum int) error {
 			return err
 		}
 	}
-	return nil
+	return s.updateSchema(SchemaVersion)
 }
 
 func (s *Store) UpdateSlotWithoutAction(m *Slot) error {
-	err := s.client.Update(s.SlotPath(m.Id), m.Encode())
+	b, err := m.Encode()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = s.client.Update(s.SlotPath(m.Id), b)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -228,7 +473,9 @@ func (s *Store) UpdateSlotWithoutAction(m *Slot) error {
 	return nil
 }
 
-func (s *Store) UpdateSlot(m *Slot) error {
+func (s *Store) UpdateSlot(m *Slot) (err error) {
+	span, cl := s.startSpan("UpdateSlot", client.Attr("slot", m.Id))
+	defer func() { span.End(err) }()
 	switch m.State.Status {
 	case SLOT_STATUS_MIGRATE, SLOT_STATUS_OFFLINE,
 		SLOT_STATUS_ONLINE, SLOT_STATUS_PRE_MIGRATE:
@@ -240,14 +487,18 @@ func (s *Store) UpdateSlot(m *Slot) error {
 			return errors.Trace(ErrUnknownSlotStatus)
 		}
 	}
-	err := s.client.Update(s.SlotPath(m.Id), m.Encode())
+	b, err := m.Encode()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	err = cl.Update(s.SlotPath(m.Id), b)
 	if err != nil {
 		return errors.Trace(err)
 	}
 	if m.State.Status == SLOT_STATUS_MIGRATE {
-		err = s.NewAction(ACTION_TYPE_SLOT_MIGRATE, m, "", true)
+		err = s.EmitAction(ACTION_TYPE_SLOT_MIGRATE, m, "")
 	} else {
-		err = s.NewAction(ACTION_TYPE_SLOT_CHANGED, m, "", true)
+		err = s.EmitAction(ACTION_TYPE_SLOT_CHANGED, m, "")
 	}
 	if err != nil {
 		return errors.Trace(err)
@@ -260,19 +511,17 @@ func (s *Store) DeleteSlot(sid int) error {
 	return s.client.Delete(s.SlotPath(sid))
 }
 
-func (s *Store) ListGroup() (map[int]*ServerGroup, error) {
-	paths, err := s.client.List(s.GroupDir(), false)
+func (s *Store) ListGroup() (_ map[int]*ServerGroup, err error) {
+	span, cl := s.startSpan("ListGroup")
+	defer func() { span.End(err) }()
+	values, err := client.ListWithValues(cl, s.GroupDir(), false)
 	if err != nil {
 		return nil, err
 	}
 	group := make(map[int]*ServerGroup)
-	for _, path := range paths {
-		b, err := s.client.Read(path, true)
-		if err != nil {
-			return nil, err
-		}
+	for _, b := range values {
 		g := &ServerGroup{}
-		if err := jsonDecode(g, b); err != nil {
+		if err := g.Decode(b); err != nil {
 			return nil, err
 		}
 		group[g.Id] = g
@@ -280,13 +529,15 @@ func (s *Store) ListGroup() (map[int]*ServerGroup, error) {
 	return group, nil
 }
 
-func (s *Store) LoadGroup(gid int, must bool) (*ServerGroup, error) {
-	b, err := s.client.Read(s.GroupPath(gid), must)
+func (s *Store) LoadGroup(gid int, must bool) (_ *ServerGroup, err error) {
+	span, cl := s.startSpan("LoadGroup", client.Attr("group", gid))
+	defer func() { span.End(err) }()
+	b, err := cl.Read(s.GroupPath(gid), must)
 	if err != nil || b == nil {
 		return nil, err
 	}
 	g := &ServerGroup{}
-	if err := jsonDecode(g, b); err != nil {
+	if err := g.Decode(b); err != nil {
 		return nil, err
 	}
 	return g, nil
@@ -308,21 +559,31 @@ func (s *Store) GroupExists(gid int) (bool, error) {
 	return g != nil, nil
 }
 
-func (s *Store) UpdateGroup(g *ServerGroup) error {
-	return s.client.Update(s.GroupPath(g.Id), g.Encode())
+func (s *Store) UpdateGroup(g *ServerGroup) (err error) {
+	span, cl := s.startSpan("UpdateGroup", client.Attr("group", g.Id))
+	defer func() { span.End(err) }()
+	b, err := g.Encode()
+	if err != nil {
+		return err
+	}
+	return cl.Update(s.GroupPath(g.Id), b)
 }
 
-func (s *Store) DeleteGroup(gid int) error {
-	return s.client.Delete(s.GroupPath(gid))
+func (s *Store) DeleteGroup(gid int) (err error) {
+	span, cl := s.startSpan("DeleteGroup", client.Attr("group", gid))
+	defer func() { span.End(err) }()
+	return cl.Delete(s.GroupPath(gid))
 }
 
-func (s *Store) GetServer(addr string, must bool) (*Server, error) {
-	data, err := s.client.Read(s.ServerPath(addr), must)
+func (s *Store) GetServer(addr string, must bool) (_ *Server, err error) {
+	span, cl := s.startSpan("GetServer", client.Attr("server", addr))
+	defer func() { span.End(err) }()
+	data, err := cl.Read(s.ServerPath(addr), must)
 	if err != nil || data == nil {
 		return nil, err
 	}
 	var server Server
-	if err := json.Unmarshal(data, &server); err != nil {
+	if err := server.Decode(data); err != nil {
 		return nil, err
 	}
 
@@ -335,24 +596,62 @@ func (s *Store) GetServerByPath(path string, must bool) (*Server, error) {
 		return nil, err
 	}
 	var server Server
-	if err := json.Unmarshal(data, &server); err != nil {
+	if err := server.Decode(data); err != nil {
 		return nil, err
 	}
 
 	return &server, nil
 }
 
-func (s *Store) UpdateServer(server *Server) error {
-	return s.client.Update(s.ServerPath(server.Addr), server.Encode())
+func (s *Store) UpdateServer(server *Server) (err error) {
+	span, cl := s.startSpan("UpdateServer", client.Attr("server", server.Addr))
+	defer func() { span.End(err) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.GetServer(server.Addr, false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	b, err := server.Encode()
+	if err != nil {
+		return err
+	}
+	if err := cl.Update(s.ServerPath(server.Addr), b); err != nil {
+		return errors.Trace(err)
+	}
+	switch {
+	case old == nil:
+		return s.emitAfterWrite(ACTION_TYPE_SERVER_ADDED, server)
+	case old.Type != server.Type:
+		return s.emitAfterWrite(ACTION_TYPE_SERVER_TYPE_CHANGED, server)
+	}
+	return nil
 }
 
-func (s *Store) DeleteServer(addr string) error {
-	return s.client.Delete(s.ServerPath(addr))
+func (s *Store) DeleteServer(addr string) (err error) {
+	span, cl := s.startSpan("DeleteServer", client.Attr("server", addr))
+	defer func() { span.End(err) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	server, err := s.GetServer(addr, false)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if err := cl.Delete(s.ServerPath(addr)); err != nil {
+		return errors.Trace(err)
+	}
+	if server == nil {
+		return nil
+	}
+	return s.emitAfterWrite(ACTION_TYPE_SERVER_REMOVED, server)
 }
 
 func (s *Store) CreateActoinInOrderer(a *Action) (p string, err error) {
-	path := GetWatchActionDir(s.product)
-	return s.client.CreateInOrder(path, a.Encode())
+	b, err := a.Encode()
+	if err != nil {
+		return "", err
+	}
+	return s.client.CreateInOrder(s.ActionDir(), b)
 }
 
 func (s *Store) DeleteAction(id int) error {
@@ -360,7 +659,7 @@ func (s *Store) DeleteAction(id int) error {
 }
 
 func (s *Store) WatchActions() (<-chan client.Event, []string, error) {
-	return s.client.WatchInOrder(GetWatchActionDir(s.product))
+	return s.client.WatchInOrder(s.ActionDir())
 }
 
 func ValidateProduct(name string) error {
@@ -370,7 +669,9 @@ func ValidateProduct(name string) error {
 	return errors.Errorf("bad product name = %s", name)
 }
 
-func (s *Store) SetSlotRange(productName string, fromSlot, toSlot, groupId int, status SlotStatus) error {
+func (s *Store) SetSlotRange(productName string, fromSlot, toSlot, groupId int, status SlotStatus) (err error) {
+	span, _ := s.startSpan("SetSlotRange", client.Attr("from", fromSlot), client.Attr("to", toSlot), client.Attr("group", groupId))
+	defer func() { span.End(err) }()
 	if status != SLOT_STATUS_OFFLINE && status != SLOT_STATUS_ONLINE {
 		return errors.New("invalid status")
 	}
@@ -406,19 +707,29 @@ func (s *Store) SetSlotRange(productName string, fromSlot, toSlot, groupId int,
 		GroupId: groupId,
 		Status:  status,
 	}
-	err = s.NewAction(ACTION_TYPE_MULTI_SLOT_CHANGED, param, "", true)
+	err = s.EmitAction(ACTION_TYPE_MULTI_SLOT_CHANGED, param, "")
 	return errors.Trace(err)
 }
 
 // todo only need sg id
-func (s *Store) GetServers(sg *ServerGroup) ([]Server, error) {
-	var ret []Server
+func (s *Store) GetServers(sg *ServerGroup) (_ []Server, err error) {
+	span, cl := s.startSpan("GetServers", client.Attr("group", sg.Id))
+	defer func() { span.End(err) }()
+	paths := make([]string, 0, len(sg.Servers))
 	for _, server := range sg.Servers {
-		s, err := s.GetServer(server.Addr, true)
-		if err != nil {
+		paths = append(paths, s.ServerPath(server.Addr))
+	}
+	values, err := client.ReadMany(cl, paths, true)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	var ret []Server
+	for _, data := range values {
+		var server Server
+		if err := server.Decode(data); err != nil {
 			return nil, errors.Trace(err)
 		}
-		ret = append(ret, *s)
+		ret = append(ret, server)
 	}
 	return ret, nil
 }
@@ -454,8 +765,11 @@ func (s *Store) CheckAsOnlyMaster(sg *ServerGroup) (*Server, error) {
 // ------------ cli ------------
 
 func (s *Store) RegisterActiveCli(l *Lock) error {
-	name := l.Name()
-	return s.client.Update(s.CliPath(name), l.Encode())
+	b, err := l.Encode()
+	if err != nil {
+		return err
+	}
+	return s.client.Update(s.CliPath(l.Name()), b)
 }
 
 func (s *Store) UnregisterActiveCli(name string) error {
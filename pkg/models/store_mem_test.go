@@ -0,0 +1,139 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/IceFireDB/kit/pkg/logger"
+	memclient "github.com/IceFireDB/kit/pkg/models/client/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemClientNamespace(t *testing.T) {
+	c, err := NewClientFromDSN("mem:///staging")
+	assert.Nil(t, err)
+	defer c.Close()
+
+	s := NewStore(c, productName)
+	assert.Nil(t, s.UpdateGroup(NewServerGroup(productName, 1)))
+	groups, err := s.ListGroup()
+	assert.Nil(t, err)
+	assert.Len(t, groups, 1)
+
+	paths, err := c.List(GroupDir(productName), true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{GroupPath(productName, 1)}, paths)
+}
+
+func TestStoreRoot(t *testing.T) {
+	c := memclient.New()
+	defer c.Close()
+
+	for _, product := range []string{"p1", "p2"} {
+		s := NewStoreWithRoot(c, "/staging", product)
+		assert.Nil(t, s.UpdateGroup(NewServerGroup(product, 1)))
+	}
+	assert.Nil(t, NewStore(c, "p3").UpdateGroup(NewServerGroup("p3", 1)))
+
+	s := NewStoreWithRoot(c, "staging", "p1")
+	assert.Equal(t, "/staging/p1/group/group-0001", s.GroupPath(1))
+	products, err := s.ListProducts()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"p1", "p2"}, products)
+
+	products, err = ListProducts(c, BaseDir)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"p3"}, products)
+}
+
+func TestProductLifecycle(t *testing.T) {
+	c := memclient.New()
+	defer c.Close()
+
+	s := NewStore(c, productName)
+	assert.Nil(t, s.CreateProduct(16))
+	assert.NotNil(t, s.CreateProduct(16))
+	assert.NotNil(t, NewStore(c, "bad/name").CreateProduct(16))
+
+	assert.Nil(t, s.UpdateGroup(NewServerGroup(productName, 1)))
+	proxy := &ProxyInfo{Id: "proxy-1", State: PROXY_STATE_ONLINE}
+	assert.Nil(t, s.UpdateProxy(proxy))
+
+	summary, err := s.DescribeProduct()
+	assert.Nil(t, err)
+	assert.Equal(t, &ProductSummary{
+		Name:          productName,
+		SchemaVersion: SchemaVersion,
+		Slots:         map[SlotStatus]int{SLOT_STATUS_OFFLINE: 16},
+		Groups:        1,
+		Proxies:       1,
+		OnlineProxies: 1,
+	}, summary)
+
+	err = s.DeleteProduct()
+	assert.Contains(t, err.Error(), ErrProductProxiesOnline.Error())
+	topom, err := s.LoadTopom(false)
+	assert.Nil(t, err)
+	assert.Nil(t, topom)
+	proxy.State = PROXY_STATE_OFFLINE
+	assert.Nil(t, s.UpdateProxy(proxy))
+
+	assert.Nil(t, s.Acquire(&Topom{ProductName: productName}))
+	err = s.DeleteProduct()
+	assert.Contains(t, err.Error(), ErrProductLocked.Error())
+	assert.Nil(t, s.Release())
+	assert.Nil(t, s.DeleteProduct())
+
+	exists, err := s.ProductExists()
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
+
+func TestCreateProductConcurrent(t *testing.T) {
+	c := memclient.New()
+	defer c.Close()
+
+	var created, refused int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := NewStore(c, productName).CreateProduct(16)
+			switch {
+			case err == nil:
+				atomic.AddInt32(&created, 1)
+			case strings.Contains(err.Error(), ErrProductLocked.Error()),
+				strings.Contains(err.Error(), ErrProductExists.Error()):
+				atomic.AddInt32(&refused, 1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), created)
+	assert.Equal(t, int32(7), refused)
+
+	s := NewStore(c, productName)
+	slots, err := s.Slots()
+	assert.Nil(t, err)
+	assert.Len(t, slots, 16)
+	topom, err := s.LoadTopom(false)
+	assert.Nil(t, err)
+	assert.Nil(t, topom)
+}
+
+func TestStoreLogger(t *testing.T) {
+	c := memclient.New()
+	defer c.Close()
+
+	var buf bytes.Buffer
+	s := NewStoreWithLogger(c, BaseDir, productName, logger.NewLogger("store-test", logger.WithOutput(&buf)))
+	assert.Nil(t, s.Migrate())
+	assert.Contains(t, buf.String(), "migrate product schema")
+	assert.Contains(t, buf.String(), "store-test")
+}
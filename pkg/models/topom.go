@@ -15,6 +15,10 @@ type Topom struct {
 	Sys string `json:"sys"`
 }
 
-func (t *Topom) Encode() []byte {
-	return jsonEncode(t)
+func (t *Topom) Encode() ([]byte, error) {
+	return encode(t)
+}
+
+func (t *Topom) Decode(b []byte) error {
+	return decode(t, b)
 }
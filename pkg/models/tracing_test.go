@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/IceFireDB/kit/pkg/models/client"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracing(t *testing.T) {
+	tracer := client.NewMemoryTracer()
+	c, err := NewClientWithOptions(&ClientOptions{Coordinator: "mem", Tracer: tracer})
+	assert.Nil(t, err)
+	defer c.Close()
+
+	s := NewStore(c, productName)
+	s.SetTracer(tracer)
+	g := NewServerGroup(productName, 1)
+	assert.Nil(t, s.UpdateGroup(g))
+	loaded, err := s.LoadGroup(1, true)
+	assert.Nil(t, err)
+	assert.Equal(t, g.Id, loaded.Id)
+	_, err = c.Read(GroupPath(productName, 2), true)
+	assert.True(t, errors.Is(err, client.ErrNotFound))
+
+	spans := tracer.Spans()
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	assert.Equal(t, []string{
+		"client.Update", "store.UpdateGroup",
+		"client.Read", "store.LoadGroup", "client.Read",
+	}, names)
+
+	update := spans[0]
+	assert.Equal(t, "mem", update.Attributes["backend"])
+	assert.Equal(t, GroupPath(productName, 1), update.Attributes["path"])
+	b, _ := g.Encode()
+	assert.Equal(t, len(b), update.Attributes["size"])
+
+	group := spans[1]
+	assert.Equal(t, productName, group.Attributes["product"])
+	assert.Equal(t, 1, group.Attributes["group"])
+	assert.Nil(t, group.Err)
+	assert.True(t, group.Duration() >= 0)
+
+	load := spans[3]
+	assert.Equal(t, productName, load.Attributes["product"])
+	assert.Equal(t, 1, load.Attributes["group"])
+	assert.Nil(t, load.Err)
+
+	assert.Equal(t, group.ID, update.ParentID)
+	assert.Equal(t, load.ID, spans[2].ParentID)
+	assert.Equal(t, 0, group.ParentID)
+	assert.Equal(t, 0, load.ParentID)
+	assert.Equal(t, 0, spans[4].ParentID)
+	assert.True(t, errors.Is(spans[4].Err, client.ErrNotFound))
+
+	s.SetTracer(nil)
+	_, err = s.LoadGroup(1, true)
+	assert.Nil(t, err)
+	assert.Equal(t, len(spans)+1, len(tracer.Spans()))
+}